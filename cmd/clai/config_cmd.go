@@ -0,0 +1,77 @@
+package main
+
+import (
+	"clai/internal/config"
+	"clai/internal/tools"
+	"fmt"
+	"io"
+)
+
+// runConfigCommand implements `clai config <subcommand>`.
+func runConfigCommand(cfg *config.Config, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 || args[0] != "show" {
+		fmt.Fprintln(stderr, "Usage: clai config show")
+		return exitUsage
+	}
+	shown := *cfg
+	if shown.APIKey != "" {
+		shown.APIKey = "<redacted>"
+	}
+	out, err := shown.YAML()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitError
+	}
+	source := cfg.Path
+	if !cfg.FileFound {
+		source += " (not found, using defaults)"
+	}
+	fmt.Fprintf(stdout, "# config file: %s\n%s", source, out)
+	return exitOK
+}
+
+// configureTools removes tools the config does not enable from r.
+func configureTools(r *tools.Registry, cfg config.ToolsConfig) error {
+	known := map[string]bool{}
+	for _, name := range r.Names() {
+		known[name] = true
+	}
+	keep := map[string]bool{}
+	for _, name := range cfg.Enabled {
+		if !known[name] {
+			return fmt.Errorf("config enables unknown tool %q", name)
+		}
+		keep[name] = true
+	}
+	for _, name := range cfg.Disabled {
+		if !known[name] {
+			return fmt.Errorf("config disables unknown tool %q", name)
+		}
+	}
+	for _, name := range r.Names() {
+		disabled := len(cfg.Enabled) > 0 && !keep[name]
+		for _, d := range cfg.Disabled {
+			disabled = disabled || d == name
+		}
+		if disabled {
+			r.Unregister(name)
+		}
+	}
+	if cfg.Workspace != "" {
+		if err := tools.SetWorkspace(cfg.Workspace); err != nil {
+			return fmt.Errorf("config tools: %w", err)
+		}
+	}
+	tools.SetCommandRules(tools.CommandRules{
+		Allow:     cfg.Command.Allow,
+		Deny:      cfg.Command.Deny,
+		Timeout:   cfg.Command.Timeout,
+		MaxOutput: cfg.Command.MaxOutput,
+	})
+	tools.SetSearchEngine(tools.SearchEngine{
+		URL:        cfg.Search.URL,
+		Timeout:    cfg.Search.Timeout,
+		MaxResults: cfg.Search.MaxResults,
+	})
+	return nil
+}
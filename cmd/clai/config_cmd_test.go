@@ -0,0 +1,55 @@
+package main
+
+import (
+	"clai/internal/config"
+	"clai/internal/tools"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testRegistry() *tools.Registry {
+	r := tools.NewRegistry()
+	for _, name := range []string{"calculator", "echo", "web_search"} {
+		r.Register(tools.New(name, name, func(ctx context.Context, p struct{}) (string, error) { return "", nil }))
+	}
+	return r
+}
+
+func TestConfigureTools(t *testing.T) {
+	r := testRegistry()
+	if err := configureTools(r, config.ToolsConfig{Disabled: []string{"web_search"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(r.Names()); got != "[calculator echo]" {
+		t.Errorf("after disabling: %s", got)
+	}
+
+	r = testRegistry()
+	if err := configureTools(r, config.ToolsConfig{Enabled: []string{"echo"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(r.Names()); got != "[echo]" {
+		t.Errorf("after enabling: %s", got)
+	}
+
+	if err := configureTools(testRegistry(), config.ToolsConfig{Enabled: []string{"nope"}}); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+}
+
+func TestConfigShowRedactsAPIKey(t *testing.T) {
+	cfg := config.Default()
+	cfg.APIKey = "sk-secret"
+	var stdout, stderr strings.Builder
+	if code := runConfigCommand(&cfg, []string{"show"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "sk-secret") || !strings.Contains(stdout.String(), "api_key: <redacted>") {
+		t.Errorf("api key not redacted:\n%s", stdout.String())
+	}
+	if cfg.APIKey != "sk-secret" {
+		t.Error("show must not modify the config")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"clai/internal/llm"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Exit codes for non-interactive runs.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitToolLimit   = 3
+	exitInterrupted = 130
+)
+
+type headlessOptions struct {
+	Prompt            string
+	JSON              bool
+	MaxToolIterations int
+}
+
+// buildPrompt combines prompt arguments with piped input, so that
+// `git diff | clai "review this"` sends the instruction followed by the diff.
+func buildPrompt(args []string, stdin string) string {
+	prompt := strings.TrimSpace(strings.Join(args, " "))
+	stdin = strings.TrimRight(stdin, "\n")
+	switch {
+	case prompt == "":
+		return stdin
+	case strings.TrimSpace(stdin) == "":
+		return prompt
+	default:
+		return prompt + "\n\n" + stdin
+	}
+}
+
+// runHeadless answers a single prompt without the TUI. The reply streams to
+// stdout as it is generated (or is printed as a JSON llm.Response with
+// JSON set); tool calls run automatically and are reported on stderr.
+func runHeadless(ctx context.Context, client *llm.Client, opts headlessOptions, stdout, stderr io.Writer) int {
+	if strings.TrimSpace(opts.Prompt) == "" {
+		fmt.Fprintln(stderr, "Error: no prompt given. Pass it as an argument or on stdin.")
+		return exitUsage
+	}
+
+	endsWithNewline := true
+	onEvent := func(ev llm.StreamEvent) {
+		switch ev.Type {
+		case llm.EventContent:
+			if !opts.JSON {
+				fmt.Fprint(stdout, ev.Content)
+				endsWithNewline = strings.HasSuffix(ev.Content, "\n")
+			}
+		case llm.EventToolCall:
+			for _, call := range ev.ToolCalls {
+				fmt.Fprintf(stderr, "→ %s %s\n", call.Name, string(call.Parameters))
+			}
+		}
+	}
+
+	messages := []llm.Message{{Role: "user", Content: opts.Prompt}}
+	_, final, err := client.RunAgent(ctx, messages, opts.MaxToolIterations, onEvent)
+	if !opts.JSON && !endsWithNewline {
+		fmt.Fprintln(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		switch {
+		case errors.Is(err, context.Canceled):
+			return exitInterrupted
+		case errors.Is(err, llm.ErrToolIterationLimit):
+			return exitToolLimit
+		default:
+			return exitError
+		}
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(final); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"clai/internal/llm"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeOllama answers the first request with a tool call and later ones with
+// the text "4".
+func fakeOllama(t *testing.T) *httptest.Server {
+	t.Helper()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"calculator","arguments":{"expression":"2+2"}}}]},"done":true}`)
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"4"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBuildPrompt(t *testing.T) {
+	if got := buildPrompt([]string{"review", "this"}, "diff\n"); got != "review this\n\ndiff" {
+		t.Errorf("got %q", got)
+	}
+	if got := buildPrompt(nil, "just stdin\n"); got != "just stdin" {
+		t.Errorf("got %q", got)
+	}
+	if got := buildPrompt([]string{"just args"}, ""); got != "just args" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRunHeadlessStreamsText(t *testing.T) {
+	srv := fakeOllama(t)
+	var stdout, stderr bytes.Buffer
+	code := runHeadless(context.Background(), llm.NewClient(srv.URL, "test", ""), headlessOptions{Prompt: "2+2?"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if stdout.String() != "4\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "calculator") {
+		t.Errorf("tool call not reported on stderr: %q", stderr.String())
+	}
+}
+
+func TestRunHeadlessJSON(t *testing.T) {
+	srv := fakeOllama(t)
+	var stdout, stderr bytes.Buffer
+	code := runHeadless(context.Background(), llm.NewClient(srv.URL, "test", ""), headlessOptions{Prompt: "2+2?", JSON: true}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	var resp llm.Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		t.Fatalf("stdout is not a JSON response: %v\n%s", err, stdout.String())
+	}
+	if resp.Message.Content != "4" || !resp.Done {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestRunHeadlessExitCodes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runHeadless(context.Background(), llm.NewClient("http://127.0.0.1:0", "test", ""), headlessOptions{}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("empty prompt: exit code %d, want %d", code, exitUsage)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	if code := runHeadless(context.Background(), llm.NewClient(srv.URL, "test", ""), headlessOptions{Prompt: "hi"}, &stdout, &stderr); code != exitError {
+		t.Errorf("server error: exit code %d, want %d", code, exitError)
+	}
+
+	looping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"echo","arguments":{"message":"again"}}}]},"done":true}`)
+	}))
+	defer looping.Close()
+	limited := headlessOptions{Prompt: "hi", MaxToolIterations: 2}
+	if code := runHeadless(context.Background(), llm.NewClient(looping.URL, "test", ""), limited, &stdout, &stderr); code != exitToolLimit {
+		t.Errorf("endless tool calls: exit code %d, want %d", code, exitToolLimit)
+	}
+}
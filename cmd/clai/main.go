@@ -1,19 +1,26 @@
 package main
 
 import (
+	"clai/internal/config"
+	"clai/internal/history"
 	"clai/internal/llm"
+	"clai/internal/session"
+	"clai/internal/tools"
 	"clai/internal/ui"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
 	"runtime/debug"
 
 	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -35,18 +42,81 @@ func main() {
 			os.Exit(2)
 		}
 	}()
-	// Check for TTY (interactive terminal)
-	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
-		fmt.Fprintln(os.Stderr, "Error: This program requires an interactive terminal (TTY). Exiting.")
-		os.Exit(1)
-	}
-	// Log to debug.log, overwrite each run
-	logFile, err := os.Create("debug.log")
+	_ = godotenv.Load()
+	config.AddFlags(flag.CommandLine)
+	resume := flag.Bool("resume", false, "resume the most recent session")
+	sessionID := flag.String("session", "", "resume the session with this ID")
+	jsonOutput := flag.Bool("json", false, "print the final response as JSON (non-interactive mode)")
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := config.Load(flag.CommandLine)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open debug.log for writing: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: loading config: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(cfg, flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "models" {
+		log.SetOutput(io.Discard)
+		client, err := newClient(cfg, cfg.Model, cfg.SystemPrompt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := runModelsCommand(ctx, client, flag.Args()[1:], os.Stdout, os.Stderr, isatty.IsTerminal(os.Stderr.Fd()))
+		stop()
+		os.Exit(code)
+	}
+	if err := configureTools(tools.DefaultRegistry, cfg.Tools); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	modelName, systemPrompt := cfg.Model, cfg.SystemPrompt
+
+	// Without a terminal on both ends, or when a prompt is given on the
+	// command line, answer once and exit instead of starting the TUI.
+	stdinTTY := isatty.IsTerminal(os.Stdin.Fd())
+	if flag.NArg() > 0 || *jsonOutput || !stdinTTY || !isatty.IsTerminal(os.Stdout.Fd()) {
+		log.SetOutput(io.Discard)
+		var piped []byte
+		if !stdinTTY {
+			var err error
+			if piped, err = io.ReadAll(os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: reading stdin: %v\n", err)
+				os.Exit(exitError)
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		client, err := newClient(cfg, modelName, systemPrompt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		code := runHeadless(ctx, client, headlessOptions{
+			Prompt:            buildPrompt(flag.Args(), string(piped)),
+			JSON:              *jsonOutput,
+			MaxToolIterations: cfg.MaxToolIterations,
+		}, os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
+	// Log to the configured file (debug.log by default), overwrite each run
+	logPath := ""
+	if cfg.Log.Enabled && cfg.Log.File != "" {
+		logPath = cfg.Log.File
+		logFile, err := os.Create(logPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open %s for writing: %v\n", logPath, err)
+			os.Exit(1)
+		}
+		log.SetOutput(logFile)
+	} else {
+		log.SetOutput(io.Discard)
 	}
-	log.SetOutput(logFile)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -55,45 +125,79 @@ func main() {
 		log.Println("Received SIGINT (Ctrl+C), exiting immediately.")
 		os.Exit(0)
 	}()
-	_ = godotenv.Load()
-	modelName := os.Getenv("OLLAMA_MODEL")
-	if modelName == "" {
-		modelName = "llama3.1-gpu:latest"
+
+	var sessions *session.Store
+	if dir, err := session.DefaultDir(); err != nil {
+		log.Printf("Session storage disabled: %v", err)
+	} else {
+		sessions = session.NewStore(dir)
 	}
-	host := os.Getenv("OLLAMA_HOST")
-	if host == "" {
-		host = "http://localhost:11434"
+	resumed, err := loadSession(sessions, *sessionID, *resume)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-	systemPrompt := os.Getenv("SYSTEM_PROMPT")
-	flag.Parse()
-	llmClient := llm.NewClient(host, modelName, systemPrompt)
-	chatInput := textinput.New()
-	chatInput.Prompt = "> "
-	chatInput.Placeholder = "Type your message..."
+	if resumed != nil {
+		// Continue with the model and prompt the conversation was held with
+		// unless they were overridden on the command line.
+		if resumed.Model != "" && !isFlagSet("model") {
+			modelName = resumed.Model
+		}
+		if resumed.SystemPrompt != "" && !isFlagSet("system-prompt") {
+			systemPrompt = resumed.SystemPrompt
+		}
+	}
+
+	llmClient, err := newClient(cfg, modelName, systemPrompt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	chatInput := ui.NewInput(cfg.Input.CharLimit)
 	chatInput.Focus()
-	chatInput.CharLimit = 256
-	chatInput.Width = 40
-	chatInput.Cursor.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FFAA")).Bold(true).Underline(true)
-	// Modern: no blinking cursor (Blink not supported in current bubbles/textinput)
 	spin := spinner.New()
 	spin.Spinner = spinner.Dot
 	help := help.New()
 	help.ShowAll = false
+	theme, ok := ui.ThemeByName(cfg.Theme)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown theme %q (want dark or light)\n", cfg.Theme)
+		os.Exit(exitUsage)
+	}
+	if s := cfg.Context.Strategy; s != llm.ContextSummarize && s != llm.ContextTrim {
+		fmt.Fprintf(os.Stderr, "Error: unknown context strategy %q (want %s or %s)\n", s, llm.ContextSummarize, llm.ContextTrim)
+		os.Exit(exitUsage)
+	}
+	if s := cfg.Input.History; s != history.ScopeGlobal && s != history.ScopeSession {
+		fmt.Fprintf(os.Stderr, "Error: unknown input history scope %q (want %s or %s)\n", s, history.ScopeGlobal, history.ScopeSession)
+		os.Exit(exitUsage)
+	}
+	keys := ui.DefaultKeyMap
+	if err := keys.Apply(cfg.Keys); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config keys: %v\n", err)
+		os.Exit(exitUsage)
+	}
 	m := &ui.Model{
 		Log:           viewport.New(0, 0),
 		Help:          help,
-		Keys:          ui.DefaultKeyMap,
+		Keys:          keys,
 		StatusBarText: "",
 		ActivePane:    ui.ChatPane,
 		ErrorBanner:   lipgloss.NewStyle().Background(lipgloss.Color("9")).Foreground(lipgloss.Color("15")).Padding(0, 1),
-		Theme:         ui.DarkTheme,
+		Theme:         theme,
+		LogFile:       logPath,
 	}
 	m.Theme.ApplyStyles()
 	chat := ui.ChatModel{
-		TextInput: chatInput,
-		LlmClient: llmClient,
-		Spinner:   spin,
-		Theme:     &m.Theme,
+		Input:             chatInput,
+		InputMaxHeight:    cfg.Input.MaxHeight,
+		LlmClient:         llmClient,
+		Spinner:           spin,
+		Theme:             &m.Theme,
+		MaxToolIterations: cfg.MaxToolIterations,
+		ContextStrategy:   cfg.Context.Strategy,
+		History:           loadHistory(cfg.Input.HistorySize),
+		HistoryScope:      cfg.Input.History,
 	}
 	assistantIntro := "Hello! I am your AI assistant. I can use tools to help answer your questions."
 	assistantName := "assistant"
@@ -105,7 +209,18 @@ func main() {
 	chat.Width = 80
 	chat.Height = 20
 	chat.Viewport = viewport.New(chat.Width, chat.Height)
+	// Letter keys belong to the input; scroll with the page keys or the mouse.
+	chat.Viewport.KeyMap = viewport.KeyMap{
+		PageUp:   key.NewBinding(key.WithKeys("pgup")),
+		PageDown: key.NewBinding(key.WithKeys("pgdown")),
+	}
 	chat.Viewport.SetContent(assistantIntro)
+	if sessions != nil {
+		chat.UseSessions(sessions)
+	}
+	if resumed != nil {
+		chat.LoadSession(resumed)
+	}
 	m.Chat = chat
 	opts := []tea.ProgramOption{
 		tea.WithAltScreen(),
@@ -117,3 +232,76 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// loadSession resolves the --session and --resume flags to a stored session,
+// or nil when a new conversation should be started.
+func loadSession(store *session.Store, id string, resume bool) (*session.Session, error) {
+	if id == "" && !resume {
+		return nil, nil
+	}
+	if store == nil {
+		return nil, errors.New("session storage is unavailable")
+	}
+	if id != "" {
+		return store.Load(id)
+	}
+	sess, err := store.Latest()
+	if errors.Is(err, session.ErrNotFound) {
+		log.Println("No previous session to resume; starting a new one.")
+		return nil, nil
+	}
+	return sess, err
+}
+
+// loadHistory reads the prompt history, or returns nil, disabling recall,
+// when it cannot be read.
+func loadHistory(size int) *history.History {
+	path, err := history.DefaultPath()
+	if err != nil {
+		log.Printf("Prompt history disabled: %v", err)
+		return nil
+	}
+	h, err := history.Load(path, size)
+	if err != nil {
+		log.Printf("Prompt history disabled: %v", err)
+		return nil
+	}
+	return h
+}
+
+// newClient builds the LLM client for the configured provider.
+func newClient(cfg *config.Config, model, systemPrompt string) (*llm.Client, error) {
+	provider, err := llm.NewProvider(cfg.Provider, cfg.Host, cfg.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	client := llm.NewClient(cfg.Host, model, systemPrompt)
+	client.SetProvider(provider)
+	client.SetOptions(cfg.Options)
+	client.SetContextLength(cfg.Context.Length)
+	permissions, err := tools.ParsePermissions(cfg.Tools.Policy, cfg.Tools.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("config tools: %w", err)
+	}
+	client.SetPermissions(permissions)
+	return client, nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n")
+	fmt.Fprintf(out, "  clai [flags]                    start the interactive chat\n")
+	fmt.Fprintf(out, "  clai [flags] <prompt>           answer one prompt and exit\n")
+	fmt.Fprintf(out, "  <cmd> | clai [flags] [prompt]   answer using piped input\n")
+	fmt.Fprintf(out, "  clai config show                print the effective configuration\n")
+	fmt.Fprintf(out, "  clai models pull|rm <name>      download or delete a model\n\n")
+	fmt.Fprintf(out, "Exit codes: 0 success, 1 error, 2 usage, 3 tool iteration limit, 130 interrupted.\n\n")
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"clai/internal/llm"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// runModelsCommand implements `clai models <subcommand>`. With progressLine
+// set, download progress redraws a single line as on a terminal; otherwise
+// each new status is printed once.
+func runModelsCommand(ctx context.Context, client *llm.Client, args []string, stdout, stderr io.Writer, progressLine bool) int {
+	if len(args) != 2 || (args[0] != "pull" && args[0] != "rm") {
+		fmt.Fprintln(stderr, "Usage: clai models pull <name>\n       clai models rm <name>")
+		return exitUsage
+	}
+	name := args[1]
+
+	if args[0] == "rm" {
+		if err := client.DeleteModel(ctx, name); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "deleted %s\n", name)
+		return exitOK
+	}
+
+	lastStatus := ""
+	for ev := range client.PullModel(ctx, name) {
+		switch {
+		case ev.Err != nil:
+			if progressLine && lastStatus != "" {
+				fmt.Fprintln(stderr)
+			}
+			fmt.Fprintf(stderr, "Error: %v\n", ev.Err)
+			if errors.Is(ev.Err, context.Canceled) {
+				return exitInterrupted
+			}
+			return exitError
+		case ev.Done:
+			if progressLine && lastStatus != "" {
+				fmt.Fprintln(stderr)
+			}
+			fmt.Fprintf(stdout, "pulled %s\n", name)
+			return exitOK
+		case progressLine:
+			line := ev.Progress.Status
+			if ev.Progress.Total > 0 {
+				line = fmt.Sprintf("%s %3.0f%%", line, ev.Progress.Fraction()*100)
+			}
+			fmt.Fprintf(stderr, "\r\033[K%s", line)
+			lastStatus = ev.Progress.Status
+		case ev.Progress.Status != lastStatus:
+			fmt.Fprintln(stderr, ev.Progress.Status)
+			lastStatus = ev.Progress.Status
+		}
+	}
+	// The channel closes without a final event only when ctx was cancelled.
+	fmt.Fprintln(stderr, "Error: interrupted")
+	return exitInterrupted
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"clai/internal/llm"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModelsPull(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range []string{
+			`{"status":"pulling manifest"}`,
+			`{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":10}`,
+			`{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":100}`,
+			`{"status":"success"}`,
+		} {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	defer srv.Close()
+
+	var stdout, stderr strings.Builder
+	code := runModelsCommand(context.Background(), llm.NewClient(srv.URL, "test", ""), []string{"pull", "llama3"}, &stdout, &stderr, false)
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "pulled llama3\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if want := "pulling manifest\npulling abc\nsuccess\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestModelsUsage(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := runModelsCommand(context.Background(), llm.NewClient("http://unused", "test", ""), []string{"pull"}, &stdout, &stderr, false); code != exitUsage {
+		t.Errorf("exit code %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,312 @@
+// Package config holds clai's settings and loads them from, in increasing
+// order of precedence, built-in defaults, a YAML file in the user config
+// directory, environment variables and command-line flags.
+package config
+
+import (
+	"clai/internal/history"
+	"clai/internal/llm"
+	"clai/internal/tools"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the effective configuration for a run.
+type Config struct {
+	Provider          string              `yaml:"provider"`
+	Model             string              `yaml:"model"`
+	Host              string              `yaml:"host"`
+	APIKey            string              `yaml:"api_key,omitempty"`
+	SystemPrompt      string              `yaml:"system_prompt"`
+	Theme             string              `yaml:"theme"`
+	MaxToolIterations int                 `yaml:"max_tool_iterations"`
+	Options           llm.Options         `yaml:"options,omitempty"`
+	Context           ContextConfig       `yaml:"context"`
+	Input             InputConfig         `yaml:"input"`
+	Keys              map[string][]string `yaml:"keys,omitempty"`
+	Tools             ToolsConfig         `yaml:"tools"`
+	Log               LogConfig           `yaml:"log"`
+
+	// Path is the config file that was read, or would have been.
+	Path string `yaml:"-"`
+	// FileFound reports whether Path existed.
+	FileFound bool `yaml:"-"`
+}
+
+type InputConfig struct {
+	// CharLimit caps the length of a message; 0 means no limit.
+	CharLimit int `yaml:"char_limit"`
+	// MaxHeight is how many rows the input grows to before it scrolls.
+	MaxHeight int `yaml:"max_height"`
+	// History is "global" to recall every prompt sent with up/down and
+	// ctrl+r, or "session" for only those sent in the current session.
+	History string `yaml:"history"`
+	// HistorySize is how many prompts are kept; 0 means 1000.
+	HistorySize int `yaml:"history_size,omitempty"`
+}
+
+// ContextConfig controls how long conversations are fitted into the
+// model's context window.
+type ContextConfig struct {
+	// Length is the context window in tokens, used when options.num_ctx is
+	// not set; 0 means 4096, Ollama's default.
+	Length int `yaml:"length,omitempty"`
+	// Strategy is "summarize" to replace old turns with a summary written by
+	// the model, or "trim" to drop them.
+	Strategy string `yaml:"strategy"`
+}
+
+// ToolsConfig selects which registered tools are offered to the model. An
+// empty Enabled list means every tool except those in Disabled.
+type ToolsConfig struct {
+	Enabled  []string `yaml:"enabled,omitempty"`
+	Disabled []string `yaml:"disabled,omitempty"`
+	// Policy is whether tools may run when the model calls them: "allow",
+	// "ask" for approval of each call, or "deny". Policies overrides it
+	// per tool.
+	Policy   string            `yaml:"policy"`
+	Policies map[string]string `yaml:"policies,omitempty"`
+	// Workspace is the directory the filesystem tools are confined to; empty
+	// means the current directory.
+	Workspace string `yaml:"workspace,omitempty"`
+	// Command limits the run_command tool.
+	Command CommandConfig `yaml:"command"`
+	// Search is the backend of the web_search tool.
+	Search SearchConfig `yaml:"search"`
+}
+
+// SearchConfig points web_search at a SearXNG instance, or anything that
+// answers its JSON API.
+type SearchConfig struct {
+	// URL is the search endpoint, e.g. http://localhost:8888/search; empty
+	// leaves web_search unconfigured.
+	URL string `yaml:"url,omitempty"`
+	// Timeout bounds each search.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxResults caps the results returned to the model.
+	MaxResults int `yaml:"max_results"`
+}
+
+// CommandConfig limits what run_command runs. Allow and Deny list command
+// patterns such as "go test" or "rm -rf"; flags match in any order. These
+// rules are not a sandbox.
+type CommandConfig struct {
+	// Allow, if not empty, lists the only commands that may run.
+	Allow []string `yaml:"allow,omitempty"`
+	// Deny lists commands that never run, even if Allow admits them. It
+	// replaces the default list, which denies shells, wrappers such as env
+	// and xargs, sudo, recursive rm and git push.
+	Deny []string `yaml:"deny,omitempty"`
+	// Timeout is how long a command may run before it is killed, e.g. 30s.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxOutput caps the stdout and the stderr returned to the model, in
+	// bytes each; 0 means 8192.
+	MaxOutput int `yaml:"max_output,omitempty"`
+}
+
+type LogConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	File    string `yaml:"file"`
+}
+
+// Default returns the built-in settings.
+func Default() Config {
+	return Config{
+		Provider:          "ollama",
+		Model:             "llama3.1-gpu:latest",
+		Host:              "http://localhost:11434",
+		Theme:             "dark",
+		MaxToolIterations: 5,
+		Context:           ContextConfig{Strategy: llm.ContextSummarize},
+		Input:             InputConfig{MaxHeight: 8, History: history.ScopeGlobal},
+		Log:               LogConfig{Enabled: true, File: "debug.log"},
+		Tools: ToolsConfig{
+			Policy: string(tools.PolicyAsk),
+			Policies: map[string]string{
+				"calculator": string(tools.PolicyAllow),
+				"echo":       string(tools.PolicyAllow),
+				"read_file":  string(tools.PolicyAllow),
+				"list_dir":   string(tools.PolicyAllow),
+				"grep":       string(tools.PolicyAllow),
+				"glob":       string(tools.PolicyAllow),
+			},
+			Command: CommandConfig{
+				Deny:    append([]string(nil), tools.DefaultDeniedCommands...),
+				Timeout: 2 * time.Minute,
+			},
+			Search: SearchConfig{Timeout: 10 * time.Second, MaxResults: 5},
+		},
+	}
+}
+
+// DefaultPath is config.yaml in the clai directory under the user config
+// directory, e.g. ~/.config/clai/config.yaml on Linux.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding config directory: %w", err)
+	}
+	return filepath.Join(dir, "clai", "config.yaml"), nil
+}
+
+// AddFlags registers the command-line overrides on fs. Load only applies
+// the flags that were actually set, so unset flags never mask the file or
+// environment.
+func AddFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "path to the config file (env CLAI_CONFIG)")
+	fs.String("provider", "", "backend: ollama or openai (env CLAI_PROVIDER)")
+	fs.String("model", "", "model name (env OLLAMA_MODEL)")
+	fs.String("host", "", "server URL (env OLLAMA_HOST)")
+	fs.String("system-prompt", "", "system prompt (env SYSTEM_PROMPT)")
+	fs.String("theme", "", "color theme: dark or light (env CLAI_THEME)")
+	fs.Int("max-tool-iterations", 0, "maximum rounds of tool calls per message (env CLAI_MAX_TOOL_ITERATIONS)")
+	fs.String("log-file", "", "debug log file (env CLAI_LOG_FILE)")
+}
+
+// Load builds the effective configuration. fs must have been parsed after
+// AddFlags; it may be nil to skip flags.
+func Load(fs *flag.FlagSet) (*Config, error) {
+	cfg := Default()
+
+	path, explicit := flagValue(fs, "config")
+	if env := os.Getenv("CLAI_CONFIG"); !explicit && env != "" {
+		path, explicit = env, true
+	}
+	if !explicit {
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Path = path
+	if err := cfg.loadFile(path); err != nil {
+		if !errors.Is(err, os.ErrNotExist) || explicit {
+			return nil, err
+		}
+	} else {
+		cfg.FileFound = true
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.applyFlags(fs); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// envVars maps environment variables to the setting they override.
+var envVars = []struct {
+	name  string
+	apply func(c *Config, v string) error
+}{
+	{"CLAI_PROVIDER", func(c *Config, v string) error { c.Provider = v; return nil }},
+	{"CLAI_API_KEY", func(c *Config, v string) error { c.APIKey = v; return nil }},
+	{"OLLAMA_MODEL", func(c *Config, v string) error { c.Model = v; return nil }},
+	{"OLLAMA_HOST", func(c *Config, v string) error { c.Host = v; return nil }},
+	{"SYSTEM_PROMPT", func(c *Config, v string) error { c.SystemPrompt = v; return nil }},
+	{"CLAI_THEME", func(c *Config, v string) error { c.Theme = v; return nil }},
+	{"CLAI_MAX_TOOL_ITERATIONS", func(c *Config, v string) error { return setInt(&c.MaxToolIterations, v) }},
+	{"CLAI_LOG_FILE", func(c *Config, v string) error { c.Log.File = v; return nil }},
+	{"CLAI_SEARCH_URL", func(c *Config, v string) error { c.Tools.Search.URL = v; return nil }},
+}
+
+func (c *Config) applyEnv() error {
+	for _, ev := range envVars {
+		v := os.Getenv(ev.name)
+		if v == "" {
+			continue
+		}
+		if err := ev.apply(c, v); err != nil {
+			return fmt.Errorf("%s: %w", ev.name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) applyFlags(fs *flag.FlagSet) error {
+	if fs == nil {
+		return nil
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		v := f.Value.String()
+		switch f.Name {
+		case "provider":
+			c.Provider = v
+		case "model":
+			c.Model = v
+		case "host":
+			c.Host = v
+		case "system-prompt":
+			c.SystemPrompt = v
+		case "theme":
+			c.Theme = v
+		case "max-tool-iterations":
+			if e := setInt(&c.MaxToolIterations, v); e != nil && err == nil {
+				err = fmt.Errorf("-%s: %w", f.Name, e)
+			}
+		case "log-file":
+			c.Log.File = v
+		}
+	})
+	return err
+}
+
+// flagValue returns the value of a flag and whether it was set.
+func flagValue(fs *flag.FlagSet, name string) (string, bool) {
+	if fs == nil {
+		return "", false
+	}
+	var value string
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			value, set = f.Value.String(), true
+		}
+	})
+	return value, set
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", v)
+	}
+	*dst = n
+	return nil
+}
+
+// YAML renders the configuration in the config file format.
+func (c *Config) YAML() (string, error) {
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return "", fmt.Errorf("encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("encoding config: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseFlags(t *testing.T, args ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("clai", flag.ContinueOnError)
+	AddFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+provider: openai
+model: from-file
+host: http://file:11434
+theme: light
+max_tool_iterations: 2
+options:
+  temperature: 0.2
+  keep_alive: 10m
+keys:
+  stop: [ctrl+x]
+tools:
+  disabled: [web_search]
+  search:
+    url: http://file:8888/search
+    timeout: 3s
+`)
+	t.Setenv("CLAI_CONFIG", path)
+	t.Setenv("OLLAMA_MODEL", "")
+	t.Setenv("OLLAMA_HOST", "http://env:11434")
+	t.Setenv("CLAI_MAX_TOOL_ITERATIONS", "3")
+	t.Setenv("CLAI_SEARCH_URL", "http://env:8888/search")
+
+	cfg, err := Load(parseFlags(t, "-max-tool-iterations", "4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.FileFound || cfg.Path != path {
+		t.Errorf("config file not recorded: %q found=%v", cfg.Path, cfg.FileFound)
+	}
+	if o := cfg.Options; o.Temperature == nil || *o.Temperature != 0.2 || o.KeepAlive != "10m" {
+		t.Errorf("options not read from the file: %s", o)
+	}
+	if cfg.Provider != "openai" {
+		t.Errorf("provider = %q, want openai from the file", cfg.Provider)
+	}
+	if cfg.Model != "from-file" {
+		t.Errorf("file should win over defaults, got model %q", cfg.Model)
+	}
+	if cfg.Host != "http://env:11434" {
+		t.Errorf("env should win over file, got host %q", cfg.Host)
+	}
+	if s := cfg.Tools.Search; s.URL != "http://env:8888/search" || s.Timeout != 3*time.Second || s.MaxResults != 5 {
+		t.Errorf("search settings = %+v", s)
+	}
+	if cfg.MaxToolIterations != 4 {
+		t.Errorf("flag should win over env, got %d", cfg.MaxToolIterations)
+	}
+	if cfg.Theme != "light" || cfg.Keys["stop"][0] != "ctrl+x" || cfg.Tools.Disabled[0] != "web_search" {
+		t.Errorf("file settings lost: %+v", cfg)
+	}
+	if cfg.Input.CharLimit != Default().Input.CharLimit || cfg.Log.File != "debug.log" || cfg.Context.Strategy != "summarize" || cfg.Input.History != "global" {
+		t.Errorf("defaults for unset keys lost: %+v", cfg)
+	}
+}
+
+func TestLoadMissingFiles(t *testing.T) {
+	t.Setenv("CLAI_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg, err := Load(parseFlags(t))
+	if err != nil {
+		t.Fatalf("a missing default config file should be fine: %v", err)
+	}
+	if cfg.FileFound {
+		t.Error("no file should have been found")
+	}
+
+	if _, err := Load(parseFlags(t, "-config", filepath.Join(t.TempDir(), "nope.yaml"))); err == nil {
+		t.Error("an explicitly requested config file must exist")
+	}
+}
+
+func TestLoadRejectsBadValues(t *testing.T) {
+	t.Setenv("CLAI_CONFIG", writeConfig(t, "model: [not, a, string]\n"))
+	if _, err := Load(nil); err == nil {
+		t.Error("expected a parse error")
+	}
+
+	t.Setenv("CLAI_CONFIG", writeConfig(t, ""))
+	t.Setenv("CLAI_MAX_TOOL_ITERATIONS", "lots")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "CLAI_MAX_TOOL_ITERATIONS") {
+		t.Errorf("expected an env error, got %v", err)
+	}
+}
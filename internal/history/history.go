@@ -0,0 +1,206 @@
+// Package history keeps the prompts sent from the chat input so they can be
+// recalled in later runs.
+//
+// The history is a JSONL file with one entry per line. Entries are only
+// ever appended while clai runs; Load rewrites the file without duplicates
+// once it has grown past twice the size limit.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLimit is how many prompts are kept when no limit is configured.
+const DefaultLimit = 1000
+
+// The scopes prompts are recalled from: every prompt ever sent, or only
+// those sent in the current session.
+const (
+	ScopeGlobal  = "global"
+	ScopeSession = "session"
+)
+
+// Entry is one sent prompt.
+type Entry struct {
+	Text string `json:"text"`
+	// Session is the ID of the session the prompt was sent in, if any.
+	Session string    `json:"session,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// History is the list of sent prompts, oldest first.
+type History struct {
+	path    string
+	limit   int
+	entries []Entry
+}
+
+// DefaultPath is history.jsonl beside the session directory:
+// $XDG_DATA_HOME/clai/history.jsonl, falling back to
+// ~/.local/share/clai/history.jsonl.
+func DefaultPath() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("finding home directory: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "clai", "history.jsonl"), nil
+}
+
+// Load reads the history file at path, keeping the newest limit entries;
+// limit 0 means DefaultLimit. A missing file is an empty history. An empty
+// path gives a history that is never written to disk.
+func Load(path string, limit int) (*History, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	h := &History{path: path, limit: limit}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening history: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	lines := 0
+	for scanner.Scan() {
+		var e Entry
+		// A line torn by a crash should not cost the rest of the history.
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Text == "" {
+			continue
+		}
+		lines++
+		h.entries = append(h.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	h.entries = dedupe(h.entries)
+	if len(h.entries) > limit {
+		h.entries = h.entries[len(h.entries)-limit:]
+	}
+	if lines > 2*limit {
+		if err := h.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// dedupe drops every entry whose text appears again later in the same
+// session, so each prompt is recalled once, at its most recent position.
+func dedupe(entries []Entry) []Entry {
+	type key struct{ text, session string }
+	seen := make(map[key]bool, len(entries))
+	out := make([]Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		k := key{entries[i].Text, entries[i].Session}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, entries[i])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Add records a sent prompt. Repeating the latest prompt of the same
+// session adds nothing.
+func (h *History) Add(text, session string) error {
+	if text == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1].Text == text && h.entries[n-1].Session == session {
+		return nil
+	}
+	e := Entry{Text: text, Session: session, Time: time.Now()}
+	h.entries = dedupe(append(h.entries, e))
+	if len(h.entries) > h.limit {
+		h.entries = h.entries[len(h.entries)-h.limit:]
+	}
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	defer f.Close()
+	return writeEntry(f, e)
+}
+
+// Prompts returns the texts of the recorded prompts, oldest first, without
+// duplicates. A non-empty session limits them to that session.
+func (h *History) Prompts(session string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := h.entries[i]
+		if (session != "" && e.Session != session) || seen[e.Text] {
+			continue
+		}
+		seen[e.Text] = true
+		out = append(out, e.Text)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// rewrite replaces the file with the entries in memory.
+func (h *History) rewrite() error {
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("rewriting history: %w", err)
+	}
+	for _, e := range h.entries {
+		if err = writeEntry(f, e); err != nil {
+			break
+		}
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, h.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rewriting history: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(f *os.File, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding history entry: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryPersistsAndSkipsDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clai", "history.jsonl")
+	h, err := Load(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, add := range []struct{ text, session string }{
+		{"hello", "a"},
+		{"hello", "a"},
+		{"multi\nline", "a"},
+		{"hello", "b"},
+		{"ls", "b"},
+	} {
+		if err := h.Add(add.text, add.session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded, err := Load(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%q", reloaded.Prompts("")); got != `["multi\nline" "hello" "ls"]` {
+		t.Errorf("global prompts = %s", got)
+	}
+	if got := fmt.Sprintf("%q", reloaded.Prompts("a")); got != `["hello" "multi\nline"]` {
+		t.Errorf("session prompts = %s", got)
+	}
+}
+
+func TestLoadTrimsToLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	var lines []string
+	for i := 0; i < 7; i++ {
+		lines = append(lines, fmt.Sprintf(`{"text":"p%d"}`, i))
+	}
+	lines = append(lines, `{"text":"torn`)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h, err := Load(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(h.Prompts("")); got != "[p4 p5 p6]" {
+		t.Errorf("prompts = %s", got)
+	}
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "\n"); n != 3 {
+		t.Errorf("file should be rewritten with 3 entries, has %d lines", n)
+	}
+}
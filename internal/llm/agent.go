@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"clai/internal/tools"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// DefaultMaxToolIterations bounds how many rounds of tool calls a single user
+// turn may trigger before the agent loop gives up on a final answer.
+const DefaultMaxToolIterations = 5
+
+// ErrToolIterationLimit is returned when the model keeps requesting tools
+// after the configured number of rounds.
+var ErrToolIterationLimit = errors.New("model was still calling tools after the iteration limit")
+
+// ExecuteToolCall runs a single tool call, if the tool's policy allows it
+// without asking, and wraps the outcome in a "tool" message ready to be
+// appended to the conversation. Failures and refusals are reported to the
+// model as the message content rather than aborting the loop, so it can
+// correct its arguments or answer without the tool. Calls whose policy is
+// ask are refused, as there is nobody to ask, and so are calls to tools
+// that change files, as nobody can review the diff; an interactive caller
+// asks first and runs approved calls with RunToolCall.
+func (c *Client) ExecuteToolCall(ctx context.Context, call ToolCall) Message {
+	if call.ArgumentsError() != nil {
+		// Reported without running, whatever the policy.
+		return c.RunToolCall(ctx, call)
+	}
+	switch c.permissions.Policy(call.Name) {
+	case tools.PolicyDeny:
+		return DeniedToolCall(call, "the tool policy forbids it")
+	case tools.PolicyAsk:
+		return DeniedToolCall(call, "it needs the user's approval, which cannot be given here")
+	}
+	if _, ok := c.registry.Previewer(call.Name); ok {
+		return DeniedToolCall(call, "its changes must be reviewed by the user, which cannot be done here")
+	}
+	return c.RunToolCall(ctx, call)
+}
+
+// RunToolCall runs a tool call regardless of its policy. A call whose
+// arguments are not valid JSON is answered with the error instead.
+func (c *Client) RunToolCall(ctx context.Context, call ToolCall) Message {
+	var result string
+	err := call.ArgumentsError()
+	if err == nil {
+		result, err = c.registry.Execute(ctx, call.Name, call.Parameters)
+	}
+	if err != nil {
+		log.Printf("tool %s failed: %v", call.Name, err)
+		result = fmt.Sprintf("error: %v", err)
+	}
+	return Message{Role: "tool", Content: result, ToolName: call.Name}
+}
+
+// DeniedToolCall is the "tool" message telling the model that a call was
+// not run, and why.
+func DeniedToolCall(call ToolCall, reason string) Message {
+	log.Printf("tool %s not run: %s", call.Name, reason)
+	return Message{Role: "tool", Content: fmt.Sprintf("error: %s was not run because %s", call.Name, reason), ToolName: call.Name}
+}
+
+// RunAgent drives the tool loop without a UI: it streams a reply, executes
+// any tools the model asks for, and re-queries until the model answers
+// without tools. onEvent, if set, sees every stream event as it arrives.
+// It returns the conversation including every new message, and the final
+// response.
+func (c *Client) RunAgent(ctx context.Context, messages []Message, maxIterations int, onEvent func(StreamEvent)) ([]Message, Response, error) {
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxToolIterations
+	}
+	for iteration := 0; ; iteration++ {
+		var final Response
+		var streamErr error
+		for ev := range c.SendMessageStream(ctx, messages) {
+			if onEvent != nil {
+				onEvent(ev)
+			}
+			switch ev.Type {
+			case EventDone:
+				final = ev.Response
+			case EventError:
+				streamErr = ev.Err
+			}
+		}
+		if streamErr == nil && !final.Done {
+			// A cancelled stream may close without delivering its error.
+			streamErr = ctx.Err()
+			if streamErr == nil {
+				streamErr = errStreamEnded
+			}
+		}
+		if streamErr != nil {
+			return messages, final, streamErr
+		}
+		messages = append(messages, final.Message)
+		if len(final.Message.ToolCalls) == 0 {
+			return messages, final, nil
+		}
+		if iteration >= maxIterations {
+			return messages, final, ErrToolIterationLimit
+		}
+		for _, call := range final.Message.ToolCalls {
+			messages = append(messages, c.ExecuteToolCall(ctx, call))
+		}
+	}
+}
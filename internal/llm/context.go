@@ -0,0 +1,249 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultContextLength is the context window assumed when neither the
+// num_ctx option nor SetContextLength says otherwise. It is Ollama's
+// default.
+const DefaultContextLength = 4096
+
+// Context strategies: what to do with a conversation that no longer fits.
+const (
+	// ContextTrim drops the oldest turns.
+	ContextTrim = "trim"
+	// ContextSummarize replaces the oldest turns with a summary written by
+	// the model.
+	ContextSummarize = "summarize"
+)
+
+const (
+	// charsPerToken is a rough average for English text and code; it errs
+	// towards overestimating, which only makes trimming slightly early.
+	charsPerToken = 4
+	// messageOverhead covers the role and the template tokens around each
+	// message.
+	messageOverhead = 4
+	// maxReplyReserve caps the room kept free for the reply.
+	maxReplyReserve = 2048
+)
+
+const summaryPrompt = `You summarise conversations between a user and an assistant.
+Write a concise summary of the conversation below so the assistant can continue it
+without the original messages. Keep names, facts, decisions, file names, code
+identifiers and any open questions or tasks. Write plain prose, no preamble.`
+
+// SummaryPrefix starts the content of every summary message made by Compact.
+const SummaryPrefix = "Summary of the earlier conversation:\n"
+
+// EstimateTokens guesses how many tokens text takes. No tokenizer is
+// available for every model, so it counts characters.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateMessageTokens guesses the tokens one message takes, tool calls
+// included.
+func EstimateMessageTokens(m Message) int {
+	n := messageOverhead + EstimateTokens(m.Content)
+	for _, call := range m.ToolCalls {
+		n += EstimateTokens(call.Name) + EstimateTokens(string(call.Parameters))
+	}
+	return n
+}
+
+// EstimateConversationTokens sums EstimateMessageTokens over messages.
+func EstimateConversationTokens(messages []Message) int {
+	n := 0
+	for _, m := range messages {
+		n += EstimateMessageTokens(m)
+	}
+	return n
+}
+
+// ContextUsage is how much of the context window a conversation fills.
+type ContextUsage struct {
+	Used  int
+	Limit int
+}
+
+// Fraction is Used over Limit; above 1 the oldest turns are being dropped.
+func (u ContextUsage) Fraction() float64 {
+	if u.Limit <= 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Limit)
+}
+
+// String summarises the usage, e.g. "1843/4096 tok (45%)".
+func (u ContextUsage) String() string {
+	return fmt.Sprintf("%s/%s tok (%.0f%%)", formatCount(u.Used), formatCount(u.Limit), u.Fraction()*100)
+}
+
+// SetContextLength sets the context window, in tokens, of models that do
+// not have num_ctx set; 0 restores DefaultContextLength.
+func (c *Client) SetContextLength(n int) {
+	c.contextLength = n
+}
+
+// ContextLength is the context window requests are fitted into.
+func (c *Client) ContextLength() int {
+	switch {
+	case c.options.NumCtx != nil && *c.options.NumCtx > 0:
+		return *c.options.NumCtx
+	case c.contextLength > 0:
+		return c.contextLength
+	default:
+		return DefaultContextLength
+	}
+}
+
+// fixedTokens estimates what every request spends before the conversation:
+// the system prompt and the tool definitions.
+func (c *Client) fixedTokens() int {
+	n := EstimateMessageTokens(Message{Role: "system", Content: c.systemPrompt})
+	if defs := c.registry.Definitions(); len(defs) > 0 {
+		raw, _ := json.Marshal(defs)
+		n += EstimateTokens(string(raw))
+	}
+	return n
+}
+
+// replyReserve is the part of the window kept free for the reply.
+func (c *Client) replyReserve() int {
+	return min(c.ContextLength()/4, maxReplyReserve)
+}
+
+// contextBudget is how many tokens the conversation itself may take.
+func (c *Client) contextBudget() int {
+	return max(c.ContextLength()-c.replyReserve()-c.fixedTokens(), 0)
+}
+
+// ContextUsage estimates how much of the context window a request with
+// messages would fill, counting the system prompt and tool definitions.
+func (c *Client) ContextUsage(messages []Message) ContextUsage {
+	return ContextUsage{
+		Used:  c.fixedTokens() + EstimateConversationTokens(messages),
+		Limit: c.ContextLength(),
+	}
+}
+
+// NeedsCompaction reports whether messages exceed the context budget.
+func (c *Client) NeedsCompaction(messages []Message) bool {
+	return EstimateConversationTokens(messages) > c.contextBudget()
+}
+
+// leadingSystem counts the system messages, such as a summary, at the start
+// of messages. Trimming never drops them.
+func leadingSystem(messages []Message) int {
+	n := 0
+	for n < len(messages) && messages[n].Role == "system" {
+		n++
+	}
+	return n
+}
+
+// recentStart returns the index of the earliest user message from which
+// the rest of messages fits in budget. The latest turn is always kept, so
+// the result is that turn's start when even it does not fit, or 0 when
+// messages holds no user message.
+func recentStart(messages []Message, budget int) int {
+	start := -1
+	used := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		used += EstimateMessageTokens(messages[i])
+		if messages[i].Role != "user" {
+			continue
+		}
+		if start >= 0 && used > budget {
+			break
+		}
+		start = i
+	}
+	return max(start, 0)
+}
+
+// TrimContext drops the oldest turns of messages, a user message and the
+// replies and tool results that follow it, until the rest fits the context
+// budget. Leading system messages and the latest turn are always kept.
+func (c *Client) TrimContext(messages []Message) []Message {
+	budget := c.contextBudget()
+	if EstimateConversationTokens(messages) <= budget {
+		return messages
+	}
+	pinned := leadingSystem(messages)
+	start := recentStart(messages[pinned:], budget-EstimateConversationTokens(messages[:pinned]))
+	if start == 0 {
+		return messages
+	}
+	log.Printf("context: dropping %d of %d messages to fit %d tokens", start, len(messages), c.ContextLength())
+	return append(messages[:pinned:pinned], messages[pinned+start:]...)
+}
+
+// Compact asks the model to summarise the oldest turns of messages so the
+// conversation fits the context window with room to grow. It returns a
+// system message holding the summary and how many leading messages it
+// replaces; the turns filling the newest half of the budget are kept as they
+// are. A leading summary from an earlier Compact is folded into the new one.
+// It returns 0 when there is nothing old enough to summarise.
+func (c *Client) Compact(ctx context.Context, messages []Message) (Message, int, error) {
+	budget := c.contextBudget()
+	keep := recentStart(messages, budget/2)
+	pinned := leadingSystem(messages)
+	if keep <= pinned {
+		return Message{}, 0, nil
+	}
+
+	// The summary request must fit the window too: keep an earlier summary
+	// and as many of the newest messages after it as fit.
+	var head, tail strings.Builder
+	for _, m := range messages[:pinned] {
+		head.WriteString(transcriptLine(m) + "\n\n")
+	}
+	for _, m := range messages[pinned:keep] {
+		tail.WriteString(transcriptLine(m) + "\n\n")
+	}
+	text := tail.String()
+	if limit := max(budget*charsPerToken-head.Len(), 0); len(text) > limit {
+		text = strings.ToValidUTF8(text[len(text)-limit:], "")
+	}
+	text = head.String() + text
+
+	temperature := 0.0
+	summarizer := c.WithOptions(Options{Temperature: &temperature})
+	req := summarizer.newRequest([]Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: text},
+	}, nil)
+	resp, err := c.provider.Chat(ctx, req)
+	if err != nil {
+		return Message{}, 0, fmt.Errorf("summarising conversation: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Message.Content)
+	if summary == "" {
+		return Message{}, 0, fmt.Errorf("summarising conversation: the model returned an empty summary")
+	}
+	return Message{Role: "system", Content: SummaryPrefix + summary}, keep, nil
+}
+
+// transcriptLine renders a message as plain text for the summariser.
+func transcriptLine(m Message) string {
+	switch {
+	case m.Role == "tool":
+		return fmt.Sprintf("tool %s: %s", m.ToolName, m.Content)
+	case len(m.ToolCalls) > 0:
+		text := m.Role + ": " + m.Content
+		for _, call := range m.ToolCalls {
+			text += fmt.Sprintf("\n(called %s %s)", call.Name, call.Parameters)
+		}
+		return text
+	default:
+		return m.Role + ": " + m.Content
+	}
+}
@@ -0,0 +1,106 @@
+package llm_test
+
+import (
+	"clai/internal/llm"
+	"clai/internal/tools"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newContextClient returns a client whose budget for the conversation is
+// 1000 tokens less the reply reserve (250) and the system prompt (5): 745.
+func newContextClient(url string) *llm.Client {
+	client := llm.NewClient(url, "test", "s")
+	client.SetRegistry(tools.NewRegistry())
+	client.SetContextLength(1000)
+	return client
+}
+
+// conversation returns a summary followed by turns of a user message and a
+// reply of 400 characters each, about 104 tokens apiece.
+func conversation(turns int) []llm.Message {
+	messages := []llm.Message{{Role: "system", Content: llm.SummaryPrefix + "earlier"}}
+	for i := 0; i < turns; i++ {
+		messages = append(messages,
+			llm.Message{Role: "user", Content: fmt.Sprintf("%-400d", i)},
+			llm.Message{Role: "assistant", Content: strings.Repeat("a", 400)},
+		)
+	}
+	return messages
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := llm.EstimateTokens("héllo wörld!"); got != 3 {
+		t.Errorf("EstimateTokens = %d, want 3", got)
+	}
+	msg := llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{{Name: "echo", Parameters: json.RawMessage(`{"message":"hi"}`)}}}
+	if got := llm.EstimateMessageTokens(msg); got != 4+1+4 {
+		t.Errorf("EstimateMessageTokens = %d, want 9", got)
+	}
+}
+
+func TestTrimContextKeepsSummaryAndRecentTurns(t *testing.T) {
+	client := newContextClient("http://unused")
+	messages := conversation(5)
+	if !client.NeedsCompaction(messages) {
+		t.Fatal("expected five turns to overflow the budget")
+	}
+
+	trimmed := client.TrimContext(messages)
+	if len(trimmed) != 7 || trimmed[0].Content != messages[0].Content || trimmed[1].Content != messages[5].Content {
+		t.Fatalf("expected the summary and the last three turns, got %d messages starting %+v", len(trimmed), trimmed[:2])
+	}
+	if client.NeedsCompaction(trimmed) {
+		t.Error("trimmed conversation still overflows")
+	}
+	if len(messages) != 11 || messages[1].Content != fmt.Sprintf("%-400d", 0) {
+		t.Error("TrimContext modified its argument")
+	}
+
+	short := conversation(2)
+	if got := client.TrimContext(short); len(got) != len(short) {
+		t.Errorf("a conversation that fits was trimmed to %d messages", len(got))
+	}
+	if u := client.ContextUsage(short); u.Limit != 1000 || u.Used != 5+llm.EstimateConversationTokens(short) {
+		t.Errorf("unexpected usage %+v", u)
+	}
+}
+
+func TestCompactSummarisesOldTurns(t *testing.T) {
+	var req llm.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":" they counted to three \n"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	client := newContextClient(srv.URL)
+	messages := conversation(5)
+	summary, covered, err := client.Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the last turn fits in half the budget.
+	if covered != 9 {
+		t.Errorf("covered = %d, want 9", covered)
+	}
+	if summary.Role != "system" || summary.Content != llm.SummaryPrefix+"they counted to three" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if req.Options == nil || req.Options.Temperature == nil || *req.Options.Temperature != 0 {
+		t.Errorf("summary should be generated at temperature 0, got %+v", req.Options)
+	}
+	if len(req.Messages) != 2 || !strings.Contains(req.Messages[1].Content, "system: "+llm.SummaryPrefix+"earlier") ||
+		!strings.Contains(req.Messages[1].Content, "user: 3 ") || strings.Contains(req.Messages[1].Content, "user: 4 ") {
+		t.Errorf("unexpected summary request %+v", req.Messages)
+	}
+
+	if _, covered, err := client.Compact(context.Background(), conversation(1)); err != nil || covered != 0 {
+		t.Errorf("a single turn should not be compacted, got %d, %v", covered, err)
+	}
+}
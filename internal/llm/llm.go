@@ -1,14 +1,12 @@
 package llm
 
 import (
-	"bufio"
-	"bytes"
 	"clai/internal/tools"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"strings"
 )
 
 const (
@@ -33,8 +31,16 @@ type Client struct {
 	host         string
 	model        string
 	systemPrompt string
+	registry     *tools.Registry
+	permissions  *tools.Permissions
+	provider     Provider
+	options      Options
+	// contextLength is the context window when num_ctx is unset.
+	contextLength int
 }
 
+// NewClient returns a client for the Ollama server at host. Use SetProvider
+// to talk to a different kind of server.
 func NewClient(host, model, systemPrompt string) *Client {
 	if systemPrompt == "" {
 		systemPrompt = defaultSystemPrompt
@@ -43,71 +49,112 @@ func NewClient(host, model, systemPrompt string) *Client {
 		host:         host,
 		model:        model,
 		systemPrompt: systemPrompt,
+		registry:     tools.DefaultRegistry,
+		provider:     NewOllamaProvider(host),
 	}
 }
 
-type ToolCall struct {
-	Name       string          `json:"name"`
-	Parameters json.RawMessage `json:"parameters"`
+// SetProvider replaces the backend requests are sent to.
+func (c *Client) SetProvider(p Provider) {
+	c.provider = p
+}
+
+// SetRegistry replaces the tools offered to the model and executed for it.
+func (c *Client) SetRegistry(r *tools.Registry) {
+	c.registry = r
+}
+
+func (c *Client) Registry() *tools.Registry {
+	return c.registry
+}
+
+// SetPermissions sets the policies deciding which tool calls may run; nil,
+// the default, allows them all.
+func (c *Client) SetPermissions(p *tools.Permissions) {
+	c.permissions = p
+}
+
+func (c *Client) Permissions() *tools.Permissions {
+	return c.permissions
 }
 
 type Message struct {
 	Role      string     `json:"role"`
 	Content   string     `json:"content"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolName identifies which tool produced a "tool" role message.
+	ToolName string `json:"tool_name,omitempty"`
+	// Truncated marks an assistant reply whose generation was stopped early.
+	Truncated bool `json:"-"`
 }
 
 type Request struct {
-	Model    string       `json:"model"`
-	Messages []Message    `json:"messages"`
-	Tools    []tools.Tool `json:"tools,omitempty"`
-	Stream   bool         `json:"stream"`
+	Model     string             `json:"model"`
+	Messages  []Message          `json:"messages"`
+	Tools     []tools.Definition `json:"tools,omitempty"`
+	Stream    bool               `json:"stream"`
+	Options   *Options           `json:"options,omitempty"`
+	KeepAlive string             `json:"keep_alive,omitempty"`
 }
 
 type Response struct {
 	Message Message `json:"message"`
 	Done    bool    `json:"done"`
+	// Metrics are only set on the final response of a stream.
+	Metrics
 }
 
-func (c *Client) SendMessage(messages []Message) (Response, error) {
-	return c.SendMessageWithTools(messages, tools.GetAvailableTools())
+// SetOptions replaces the generation options sent with every request.
+func (c *Client) SetOptions(o Options) {
+	c.options = o
 }
 
-// SendMessageWithTools allows specifying which tools to include in the request.
-func (c *Client) SendMessageWithTools(messages []Message, toolList []tools.Tool) (Response, error) {
-	allMessages := append([]Message{{Role: "system", Content: c.systemPrompt}}, messages...)
+func (c *Client) Options() Options {
+	return c.options
+}
 
-	reqBody := Request{
-		Model:    c.model,
-		Messages: allMessages,
-		Tools:    toolList,
-		Stream:   false,
-	}
+// WithOptions returns a copy of the client whose requests use the client's
+// options overridden by o, for one-off requests such as a summary that
+// should run at temperature 0. The copy shares the registry and provider.
+func (c *Client) WithOptions(o Options) *Client {
+	dup := *c
+	dup.options = c.options.Merge(o)
+	return &dup
+}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return Response{}, err
+// newRequest fills in the model and generation options for a request.
+func (c *Client) newRequest(messages []Message, defs []tools.Definition) Request {
+	req := Request{Model: c.model, Messages: messages, Tools: defs, KeepAlive: c.options.KeepAlive}
+	if !c.options.IsZero() {
+		opts := c.options
+		req.Options = &opts
 	}
+	return req
+}
+
+func (c *Client) SendMessage(ctx context.Context, messages []Message) (Response, error) {
+	return c.SendMessageWithTools(ctx, messages, c.registry.Tools())
+}
+
+// SendMessageWithTools allows specifying which tools to include in the request.
+func (c *Client) SendMessageWithTools(ctx context.Context, messages []Message, toolList []tools.Tool) (Response, error) {
+	messages = c.TrimContext(messages)
+	allMessages := append([]Message{{Role: "system", Content: c.systemPrompt}}, messages...)
+
+	reqBody := c.newRequest(allMessages, tools.Definitions(toolList))
 
-	resp, err := http.Post(c.host+"/api/chat", "application/json", bytes.NewBuffer(jsonBody))
+	llmResp, err := c.provider.Chat(ctx, reqBody)
 	if err != nil {
 		return Response{}, err
 	}
-	defer resp.Body.Close()
 
-	// Log HTTP status and headers
-	log.Printf("Ollama response status: %s", resp.Status)
-	for k, v := range resp.Header {
-		log.Printf("Header: %s: %v", k, v)
-	}
-
-	// Limit response size to 1MB
-	const maxResponseSize = 1 << 20 // 1MB
-	limited := io.LimitReader(resp.Body, maxResponseSize)
-
-	var llmResp Response
-	if err := json.NewDecoder(limited).Decode(&llmResp); err != nil {
-		return Response{}, fmt.Errorf("error decoding LLM response (possibly too large or malformed): %w", err)
+	if len(llmResp.Message.ToolCalls) == 0 {
+		filter := c.toolCallFilter()
+		content := filter.Write(llmResp.Message.Content) + filter.Flush()
+		if len(filter.calls) > 0 {
+			llmResp.Message.ToolCalls = filter.calls
+			llmResp.Message.Content = strings.TrimSpace(content)
+		}
 	}
 
 	// Log the LLM response for debugging
@@ -118,9 +165,9 @@ func (c *Client) SendMessageWithTools(messages []Message, toolList []tools.Tool)
 }
 
 // ClassifyIntent asks the LLM if the query requires a tool call, and which tool.
-func (c *Client) ClassifyIntent(query string) (string, error) {
+func (c *Client) ClassifyIntent(ctx context.Context, query string) (string, error) {
 	// Build a system prompt listing available tools
-	availableTools := []string{"calculator", "echo", "web_search"}
+	availableTools := c.registry.Names()
 	prompt := "Does this query require a tool call? If yes, which tool? Respond with the tool name or 'none'. Available tools: " +
 		fmt.Sprintf("%v", availableTools)
 
@@ -130,102 +177,81 @@ func (c *Client) ClassifyIntent(query string) (string, error) {
 	}
 
 	// Send to LLM without any tools
-	request := Request{
-		Model:    c.model,
-		Messages: messages,
-		Stream:   false,
-	}
-	jsonBody, err := json.Marshal(request)
+	request := c.newRequest(messages, nil)
+	llmResp, err := c.provider.Chat(ctx, request)
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := http.Post(c.host+"/api/chat", "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var llmResp Response
-	if err := json.NewDecoder(resp.Body).Decode(&llmResp); err != nil {
-		return "", err
-	}
-
 	// Parse the tool name from the response
 	toolName := llmResp.Message.Content
 	return toolName, nil
 }
 
-func (c *Client) SendMessageStream(messages []Message, streamChan chan<- string) (Response, error) {
+// SendMessageStream starts a streaming chat request and returns a channel of
+// typed events. The channel always ends with a single EventDone or EventError
+// and is then closed, so callers can simply range over it. Cancelling ctx
+// aborts the request and the body read; the stream then ends with an
+// EventError wrapping ctx.Err() if anyone is still receiving. The oldest
+// turns are dropped if the conversation does not fit the context window.
+func (c *Client) SendMessageStream(ctx context.Context, messages []Message) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+	messages = c.TrimContext(messages)
 	allMessages := append([]Message{{Role: "system", Content: c.systemPrompt}}, messages...)
 
-	reqBody := Request{
-		Model:    c.model,
-		Messages: allMessages,
-		Tools:    tools.GetAvailableTools(),
-		Stream:   true,
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return Response{}, err
-	}
-
-	// Pretty print the outgoing request JSON
-	prettyReq, _ := json.MarshalIndent(reqBody, "", "  ")
-	log.Printf("[LLM-REQ] %s", string(prettyReq))
-
-	resp, err := http.Post(c.host+"/api/chat", "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return Response{}, err
-	}
+	reqBody := c.newRequest(allMessages, c.registry.Definitions())
+	reqBody.Stream = true
 
 	go func() {
-		defer resp.Body.Close()
-		defer close(streamChan)
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			raw := scanner.Bytes()
-			// Log the raw JSON response for debugging
-			// log.Printf("[LLM-RAW] %s", string(raw)) // Disabled to prevent log flooding
-			var llmResp Response
-			if err := json.Unmarshal(raw, &llmResp); err != nil {
-				// handle error, maybe send to a different channel
-				log.Printf("[LLM-RAW-ERROR] %v", err)
-				return
-			}
-			// Log the parsed LLM response message for debugging
-			prettyResp, _ := json.MarshalIndent(llmResp, "", "  ")
-			log.Printf("[LLM-RESP-STREAM] %s", string(prettyResp))
-			streamChan <- llmResp.Message.Content
-			if llmResp.Done {
-				return
-			}
-		}
+		// Pretty print the outgoing request JSON
+		prettyReq, _ := json.MarshalIndent(reqBody, "", "  ")
+		log.Printf("[LLM-REQ] %s", string(prettyReq))
+
+		c.readStream(ctx, reqBody, events)
 	}()
 
-	// This is not ideal, but we need to return a response.
-	// A better approach would be to have a single streaming function that returns a channel.
-	return Response{}, nil
+	return events
+}
+
+// toolCallFilter returns a filter that recognises calls to this client's tools
+// written into the message text.
+func (c *Client) toolCallFilter() *toolCallFilter {
+	return &toolCallFilter{isTool: func(name string) bool {
+		_, ok := c.registry.Get(name)
+		return ok
+	}}
 }
 
 func (c *Client) Model() string {
 	return c.model
 }
 
+// SetModel switches the model used for subsequent requests.
+func (c *Client) SetModel(model string) {
+	c.model = model
+}
+
 func (c *Client) Host() string {
 	return c.host
 }
 
-func (c *Client) HealthCheck() error {
-	resp, err := http.Get(c.host + "/api/tags")
-	if err != nil {
-		return fmt.Errorf("failed to connect to Ollama at %s: %w", c.host, err)
-	}
-	defer resp.Body.Close()
+func (c *Client) SystemPrompt() string {
+	return c.systemPrompt
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ollama health check failed with status: %s", resp.Status)
+// SetSystemPrompt replaces the system prompt; empty restores the default.
+func (c *Client) SetSystemPrompt(prompt string) {
+	if prompt == "" {
+		prompt = defaultSystemPrompt
 	}
-	return nil
+	c.systemPrompt = prompt
+}
+
+// ListModels returns the models installed on the server.
+func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return c.provider.ListModels(ctx)
+}
+
+func (c *Client) HealthCheck(ctx context.Context) error {
+	return c.provider.Health(ctx)
 }
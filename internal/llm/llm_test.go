@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"clai/internal/tools"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClassifyIntentListsRegisteredTools(t *testing.T) {
+	var prompt string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[0].Content
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"greet"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	registry := tools.NewRegistry()
+	registry.Register(tools.New("greet", "Greets someone.", func(ctx context.Context, p struct{}) (string, error) {
+		return "hi", nil
+	}))
+	client := NewClient(srv.URL, "test", "")
+	client.SetRegistry(registry)
+
+	name, err := client.ClassifyIntent(context.Background(), "say hi")
+	if err != nil || name != "greet" {
+		t.Fatalf("ClassifyIntent = %q, %v", name, err)
+	}
+	if !strings.Contains(prompt, "greet") || strings.Contains(prompt, "calculator") {
+		t.Errorf("prompt should list only registered tools: %q", prompt)
+	}
+}
+
+func TestExecuteToolCallFollowsPolicy(t *testing.T) {
+	client := NewClient("http://unused", "test", "")
+	client.SetPermissions(tools.NewPermissions(tools.PolicyAsk, map[string]tools.Policy{
+		"echo":       tools.PolicyAllow,
+		"calculator": tools.PolicyDeny,
+		"write_file": tools.PolicyAllow,
+	}))
+	ctx := context.Background()
+	if msg := client.ExecuteToolCall(ctx, ToolCall{Name: "echo", Parameters: json.RawMessage(`{"message":"hi"}`)}); msg.Content != "hi" {
+		t.Errorf("allowed call = %q", msg.Content)
+	}
+	// write_file is allowed, but nobody is here to review its diff.
+	for _, name := range []string{"calculator", "web_search", "write_file"} {
+		msg := client.ExecuteToolCall(ctx, ToolCall{Name: name, Parameters: json.RawMessage(`{}`)})
+		if msg.Role != "tool" || msg.ToolName != name || !strings.Contains(msg.Content, "was not run") {
+			t.Errorf("%s should be refused, got %+v", name, msg)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Metrics are the timings and token counts of one response. Ollama reports
+// them in its final chunk; Client fills in what a provider leaves out from
+// its own clock, and always measures TimeToFirstToken itself.
+type Metrics struct {
+	TotalDuration      time.Duration `json:"total_duration,omitempty"`
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`
+	PromptEvalCount    int           `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int           `json:"eval_count,omitempty"`
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`
+	// TimeToFirstToken is how long the first content or tool call took to
+	// arrive after the request was sent.
+	TimeToFirstToken time.Duration `json:"time_to_first_token,omitempty"`
+}
+
+// TokensPerSecond is the generation speed, or 0 if it is unknown.
+func (m Metrics) TokensPerSecond() float64 {
+	return tokensPerSecond(m.EvalCount, m.EvalDuration)
+}
+
+func tokensPerSecond(tokens int, d time.Duration) float64 {
+	if tokens == 0 || d <= 0 {
+		return 0
+	}
+	return float64(tokens) / d.Seconds()
+}
+
+// Usage is a running tally of Metrics over several responses.
+type Usage struct {
+	Responses        int
+	PromptTokens     int
+	CompletionTokens int
+	EvalDuration     time.Duration
+	// TimeToFirstToken is that of the first response added.
+	TimeToFirstToken time.Duration
+}
+
+func (u *Usage) Add(m Metrics) {
+	if u.Responses == 0 {
+		u.TimeToFirstToken = m.TimeToFirstToken
+	}
+	u.Responses++
+	u.PromptTokens += m.PromptEvalCount
+	u.CompletionTokens += m.EvalCount
+	u.EvalDuration += m.EvalDuration
+}
+
+// TokensPerSecond is the average generation speed over the tally.
+func (u Usage) TokensPerSecond() float64 {
+	return tokensPerSecond(u.CompletionTokens, u.EvalDuration)
+}
+
+// String summarises the tally, e.g. "512→128 tok, 41.3 tok/s".
+func (u Usage) String() string {
+	s := fmt.Sprintf("%s→%s tok", formatCount(u.PromptTokens), formatCount(u.CompletionTokens))
+	if tps := u.TokensPerSecond(); tps > 0 {
+		s += fmt.Sprintf(", %.1f tok/s", tps)
+	}
+	return s
+}
+
+// formatCount abbreviates large token counts, e.g. 12.3k.
+func formatCount(n int) string {
+	if n < 10000 {
+		return fmt.Sprint(n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
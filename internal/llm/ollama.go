@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// OllamaProvider talks to an Ollama server through its native /api routes.
+type OllamaProvider struct {
+	host string
+}
+
+func NewOllamaProvider(host string) *OllamaProvider {
+	return &OllamaProvider{host: host}
+}
+
+// streamChunk is one NDJSON line of an Ollama /api/chat streaming response.
+type streamChunk struct {
+	Response
+	Error string `json:"error,omitempty"`
+}
+
+// postJSON sends body to the given API path, aborting when ctx is cancelled.
+func (p *OllamaProvider) postJSON(ctx context.Context, path string, body any) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return http.DefaultClient.Do(req)
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, req Request) (Response, error) {
+	req.Stream = false
+	resp, err := p.postJSON(ctx, "/api/chat", req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, statusError(resp)
+	}
+
+	// Log HTTP status and headers
+	log.Printf("Ollama response status: %s", resp.Status)
+	for k, v := range resp.Header {
+		log.Printf("Header: %s: %v", k, v)
+	}
+
+	// Limit response size to 1MB
+	const maxResponseSize = 1 << 20 // 1MB
+	limited := io.LimitReader(resp.Body, maxResponseSize)
+
+	var llmResp Response
+	if err := json.NewDecoder(limited).Decode(&llmResp); err != nil {
+		return Response{}, fmt.Errorf("error decoding LLM response (possibly too large or malformed): %w", err)
+	}
+	return llmResp, nil
+}
+
+// Stream reads the NDJSON body of a streaming /api/chat request, one
+// chunk per line.
+func (p *OllamaProvider) Stream(ctx context.Context, req Request, onChunk func(Response) bool) error {
+	req.Stream = true
+	resp, err := p.postJSON(ctx, "/api/chat", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		raw := scanner.Bytes()
+		if len(raw) == 0 {
+			continue
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal(raw, &chunk); err != nil {
+			log.Printf("[LLM-RAW-ERROR] %v", err)
+			return fmt.Errorf("error decoding stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		if !onChunk(chunk.Response) || chunk.Done {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading stream: %w", err)
+	}
+	return nil
+}
+
+func (p *OllamaProvider) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Ollama at %s: %w", p.host, err)
+	}
+	return resp, nil
+}
+
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	resp, err := p.get(ctx, "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var tags struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			Size       int64     `json:"size"`
+			ModifiedAt time.Time `json:"modified_at"`
+			Details    struct {
+				Family            string `json:"family"`
+				ParameterSize     string `json:"parameter_size"`
+				QuantizationLevel string `json:"quantization_level"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("error decoding model list: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, ModelInfo{
+			Name:          m.Name,
+			Size:          m.Size,
+			ParameterSize: m.Details.ParameterSize,
+			Quantization:  m.Details.QuantizationLevel,
+			Family:        m.Details.Family,
+			ModifiedAt:    m.ModifiedAt,
+		})
+	}
+	return models, nil
+}
+
+func (p *OllamaProvider) Health(ctx context.Context) error {
+	resp, err := p.get(ctx, "/api/tags")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama health check failed with status: %s", resp.Status)
+	}
+	return nil
+}
+
+// statusError builds an error from a non-200 response, including the start
+// of the body since Ollama puts its explanation there.
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var chunk streamChunk
+	if json.Unmarshal(body, &chunk) == nil && chunk.Error != "" {
+		return fmt.Errorf("ollama returned %s: %s", resp.Status, chunk.Error)
+	}
+	return fmt.Errorf("ollama returned %s", resp.Status)
+}
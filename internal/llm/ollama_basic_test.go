@@ -3,6 +3,7 @@ package llm_test
 import (
 	"bytes"
 	"clai/internal/llm"
+	"context"
 	"encoding/json"
 	"net/http"
 	"os"
@@ -23,7 +24,7 @@ func TestOllamaHelloWorld(t *testing.T) {
 	client := llm.NewClient(host, model, "")
 
 	messages := []llm.Message{{Role: "user", Content: "Hello, world!"}}
-	resp, err := client.SendMessage(messages)
+	resp, err := client.SendMessage(context.Background(), messages)
 	if err != nil {
 		t.Fatalf("Ollama API error: %v", err)
 	}
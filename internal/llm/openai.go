@@ -0,0 +1,387 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"clai/internal/tools"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAIProvider talks to servers implementing the OpenAI chat completions
+// API, such as llama.cpp's server, vLLM and LM Studio.
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+}
+
+// NewOpenAIProvider returns a provider for the server at host. The /v1
+// prefix is added unless host already ends with it. apiKey, if set, is sent
+// as a bearer token.
+func NewOpenAIProvider(host, apiKey string) *OpenAIProvider {
+	base := strings.TrimSuffix(host, "/")
+	if !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	return &OpenAIProvider{baseURL: base, apiKey: apiKey}
+}
+
+type openaiRequest struct {
+	Model       string             `json:"model"`
+	Messages    []openaiMessage    `json:"messages"`
+	Tools       []tools.Definition `json:"tools,omitempty"`
+	Stream      bool               `json:"stream"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
+	Seed        *int               `json:"seed,omitempty"`
+	Stop        []string           `json:"stop,omitempty"`
+	// StreamOptions asks for token usage in the last streamed chunk.
+	StreamOptions *openaiStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openaiMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openaiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openaiToolCall struct {
+	Index    int    `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openaiResponse struct {
+	Choices []struct {
+		Message      openaiMessage `json:"message"`
+		Delta        openaiMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openaiUsage `json:"usage,omitempty"`
+	Error *openaiError `json:"error,omitempty"`
+}
+
+type openaiUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *openaiUsage) metrics() Metrics {
+	if u == nil {
+		return Metrics{}
+	}
+	return Metrics{PromptEvalCount: u.PromptTokens, EvalCount: u.CompletionTokens}
+}
+
+type openaiError struct {
+	Message string `json:"message"`
+}
+
+// toOpenAI converts the conversation to the chat completions format. That
+// API links each tool result to its call by ID, which Message does not
+// keep, so IDs are made up here and handed to tool messages in the order
+// the calls were made. Calls left without a result, as when a turn was
+// stopped, are answered with a placeholder, since the API refuses tool
+// calls that are not followed by their results. Only the options the API defines are sent; num_ctx,
+// repeat_penalty and keep_alive are Ollama settings.
+func toOpenAI(req Request) openaiRequest {
+	out := openaiRequest{Model: req.Model, Tools: req.Tools, Stream: req.Stream}
+	if o := req.Options; o != nil {
+		out.Temperature, out.TopP, out.Seed, out.Stop = o.Temperature, o.TopP, o.Seed, o.Stop
+	}
+	if req.Stream {
+		out.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+	}
+	var pending []string
+	for i, msg := range req.Messages {
+		if msg.Role != "tool" {
+			for _, id := range pending {
+				out.Messages = append(out.Messages, openaiMessage{Role: "tool", Content: "error: the tool was not run", ToolCallID: id})
+			}
+			pending = pending[:0]
+		}
+		om := openaiMessage{Role: msg.Role, Content: msg.Content}
+		switch {
+		case len(msg.ToolCalls) > 0:
+			for j, call := range msg.ToolCalls {
+				var tc openaiToolCall
+				tc.ID = fmt.Sprintf("call_%d_%d", i, j)
+				tc.Type = "function"
+				tc.Function.Name = call.Name
+				tc.Function.Arguments = string(call.Parameters)
+				if tc.Function.Arguments == "" {
+					tc.Function.Arguments = "{}"
+				}
+				om.ToolCalls = append(om.ToolCalls, tc)
+				pending = append(pending, tc.ID)
+			}
+		case msg.Role == "tool" && len(pending) > 0:
+			om.ToolCallID = pending[0]
+			pending = pending[1:]
+		}
+		out.Messages = append(out.Messages, om)
+	}
+	return out
+}
+
+// fromOpenAI converts a tool call in the chat completions format.
+func fromOpenAI(tc openaiToolCall) (ToolCall, error) {
+	raw, err := json.Marshal(tc)
+	if err != nil {
+		return ToolCall{}, err
+	}
+	var call ToolCall
+	if err := json.Unmarshal(raw, &call); err != nil {
+		return ToolCall{}, err
+	}
+	return call, nil
+}
+
+func (p *OpenAIProvider) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(jsonBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", p.baseURL, err)
+	}
+	return resp, nil
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req Request) (Response, error) {
+	req.Stream = false
+	resp, err := p.do(ctx, http.MethodPost, "/chat/completions", toOpenAI(req))
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, openaiStatusError(resp)
+	}
+
+	var body openaiResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return Response{}, fmt.Errorf("error decoding LLM response (possibly too large or malformed): %w", err)
+	}
+	if body.Error != nil {
+		return Response{}, fmt.Errorf("server error: %s", body.Error.Message)
+	}
+	if len(body.Choices) == 0 {
+		return Response{}, errors.New("response has no choices")
+	}
+	msg := body.Choices[0].Message
+	out := Response{Message: Message{Role: "assistant", Content: msg.Content}, Done: true, Metrics: body.Usage.metrics()}
+	for _, tc := range msg.ToolCalls {
+		call, err := fromOpenAI(tc)
+		if err != nil {
+			return Response{}, err
+		}
+		out.Message.ToolCalls = append(out.Message.ToolCalls, call)
+	}
+	return out, nil
+}
+
+// Stream reads the server-sent events of a streaming completion. Content
+// is passed on as it arrives; tool calls arrive as fragments keyed by index
+// and are reported whole with the final chunk.
+func (p *OpenAIProvider) Stream(ctx context.Context, req Request, onChunk func(Response) bool) error {
+	req.Stream = true
+	resp, err := p.do(ctx, http.MethodPost, "/chat/completions", toOpenAI(req))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return openaiStatusError(resp)
+	}
+
+	calls := map[int]*openaiToolCall{}
+	var usage *openaiUsage
+	finished, completed := false, false
+	done := func() error {
+		completed = true
+		final := Response{Message: Message{Role: "assistant"}, Done: true, Metrics: usage.metrics()}
+		indexes := make([]int, 0, len(calls))
+		for i := range calls {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		for _, i := range indexes {
+			call, err := fromOpenAI(*calls[i])
+			if err != nil {
+				return err
+			}
+			final.Message.ToolCalls = append(final.Message.ToolCalls, call)
+		}
+		onChunk(final)
+		return nil
+	}
+
+	err = readSSE(resp.Body, func(data string) (bool, error) {
+		if data == "[DONE]" {
+			return false, done()
+		}
+		var chunk openaiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return false, fmt.Errorf("error decoding stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return false, fmt.Errorf("server error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			for _, frag := range choice.Delta.ToolCalls {
+				call, ok := calls[frag.Index]
+				if !ok {
+					call = &openaiToolCall{Index: frag.Index}
+					calls[frag.Index] = call
+				}
+				if frag.ID != "" {
+					call.ID = frag.ID
+				}
+				if frag.Function.Name != "" {
+					call.Function.Name = frag.Function.Name
+				}
+				call.Function.Arguments += frag.Function.Arguments
+			}
+			if choice.FinishReason != "" {
+				finished = true
+			}
+			if choice.Delta.Content != "" {
+				if !onChunk(Response{Message: Message{Role: "assistant", Content: choice.Delta.Content}}) {
+					return false, nil
+				}
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if finished && !completed && ctx.Err() == nil {
+		// Some servers close the stream without the [DONE] sentinel once
+		// the choice has finished.
+		return done()
+	}
+	return nil
+}
+
+// readSSE calls onData with the data of each server-sent event in r until
+// onData returns false or an error. It returns nil at the end of r.
+func readSSE(r io.Reader, onData func(data string) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var data []string
+	dispatch := func() (bool, error) {
+		if len(data) == 0 {
+			return true, nil
+		}
+		event := strings.Join(data, "\n")
+		data = data[:0]
+		return onData(event)
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if more, err := dispatch(); err != nil || !more {
+				return err
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading stream: %w", err)
+	}
+	_, err := dispatch()
+	return err
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, openaiStatusError(resp)
+	}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error decoding model list: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{Name: m.ID})
+	}
+	return models, nil
+}
+
+func (p *OpenAIProvider) Health(ctx context.Context) error {
+	resp, err := p.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check failed with status: %s", resp.Status)
+	}
+	return nil
+}
+
+// openaiStatusError builds an error from a non-200 response. Servers
+// disagree on whether "error" is an object or a plain string.
+func openaiStatusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var withObject struct {
+		Error openaiError `json:"error"`
+	}
+	if json.Unmarshal(body, &withObject) == nil && withObject.Error.Message != "" {
+		return fmt.Errorf("server returned %s: %s", resp.Status, withObject.Error.Message)
+	}
+	var withString struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &withString) == nil && withString.Error != "" {
+		return fmt.Errorf("server returned %s: %s", resp.Status, withString.Error)
+	}
+	return fmt.Errorf("server returned %s", resp.Status)
+}
@@ -0,0 +1,203 @@
+package llm_test
+
+import (
+	"clai/internal/llm"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newOpenAIClient(url string) *llm.Client {
+	client := llm.NewClient(url, "test", "")
+	client.SetProvider(llm.NewOpenAIProvider(url, "secret"))
+	return client
+}
+
+func TestOpenAIStream(t *testing.T) {
+	var request map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, ": keep-alive\ndata: %s\n\n", data)
+		}
+	}))
+	defer srv.Close()
+
+	events := collect(newOpenAIClient(srv.URL).SendMessageStream(context.Background(), []llm.Message{{Role: "user", Content: "hi"}}))
+	if len(events) != 3 || events[0].Content != "Hel" || events[1].Content != "lo" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if last := events[2]; last.Type != llm.EventDone || last.Response.Message.Content != "Hello" {
+		t.Errorf("unexpected terminal event %+v", last)
+	}
+	if m := events[2].Response.Metrics; m.PromptEvalCount != 12 || m.EvalCount != 2 {
+		t.Errorf("usage not reported: %+v", m)
+	}
+	if request["stream"] != true || request["model"] != "test" || request["stream_options"] == nil {
+		t.Errorf("unexpected request %v", request)
+	}
+}
+
+func TestOpenAIStreamToolCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, data := range []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"echo","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"message\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"hi\"}"}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		// No [DONE]: the finish reason alone must end the reply.
+	}))
+	defer srv.Close()
+
+	events := collect(newOpenAIClient(srv.URL).SendMessageStream(context.Background(), nil))
+	if len(events) != 2 || events[0].Type != llm.EventToolCall || events[1].Type != llm.EventDone {
+		t.Fatalf("expected tool call then done, got %+v", events)
+	}
+	calls := events[1].Response.Message.ToolCalls
+	if len(calls) != 1 || calls[0].Name != "echo" || string(calls[0].Parameters) != `{"message":"hi"}` {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+}
+
+func TestOpenAIStreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"error":{"message":"model not loaded"}}`)
+	}))
+	defer srv.Close()
+
+	events := collect(newOpenAIClient(srv.URL).SendMessageStream(context.Background(), nil))
+	if len(events) != 1 || events[0].Type != llm.EventError || !strings.Contains(events[0].Err.Error(), "model not loaded") {
+		t.Fatalf("expected a single error event, got %+v", events)
+	}
+}
+
+func TestOpenAIChatLinksToolResults(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+		fmt.Fprintln(w, `{"choices":[{"message":{"role":"assistant","content":"4"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	resp, err := newOpenAIClient(srv.URL+"/v1").SendMessageWithTools(context.Background(), []llm.Message{
+		{Role: "user", Content: "2+2?"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{Name: "calculator", Parameters: json.RawMessage(`{"expression":"2+2"}`)}}},
+		{Role: "tool", ToolName: "calculator", Content: "4"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Content != "4" {
+		t.Errorf("content = %q", resp.Message.Content)
+	}
+	for _, want := range []string{`"id":"call_2_0"`, `"tool_call_id":"call_2_0"`, `"arguments":"{\"expression\":\"2+2\"}"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request missing %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestOpenAIAnswersUnansweredToolCalls(t *testing.T) {
+	var req struct {
+		Messages []struct {
+			Role       string `json:"role"`
+			Content    string `json:"content"`
+			ToolCallID string `json:"tool_call_id"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprintln(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	// The second call was never answered, as when the turn is stopped
+	// between the two.
+	_, err := newOpenAIClient(srv.URL+"/v1").SendMessageWithTools(context.Background(), []llm.Message{
+		{Role: "user", Content: "2+2 and 3+3?"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			{Name: "calculator", Parameters: json.RawMessage(`{"expression":"2+2"}`)},
+			{Name: "calculator", Parameters: json.RawMessage(`{"expression":"3+3"}`)},
+		}},
+		{Role: "tool", ToolName: "calculator", Content: "4"},
+		{Role: "user", Content: "never mind"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range req.Messages {
+		got = append(got, m.Role+":"+m.ToolCallID)
+	}
+	want := "[system: user: assistant: tool:call_2_0 tool:call_2_1 user:]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("messages = %v, want %s", got, want)
+	}
+}
+
+func TestProviderListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprintln(w, `{"models":[{"name":"llama3:8b","size":4661224676,"modified_at":"2026-01-02T15:04:05Z","details":{"family":"llama","parameter_size":"8.0B","quantization_level":"Q4_0"}}]}`)
+		case "/v1/models":
+			fmt.Fprintln(w, `{"object":"list","data":[{"id":"qwen2.5-coder","object":"model"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for name, want := range map[string]llm.ModelInfo{
+		llm.ProviderOllama: {
+			Name: "llama3:8b", Size: 4661224676, ParameterSize: "8.0B", Quantization: "Q4_0",
+			Family: "llama", ModifiedAt: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		llm.ProviderOpenAI: {Name: "qwen2.5-coder"},
+	} {
+		p, err := llm.NewProvider(name, srv.URL, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		models, err := p.ListModels(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(models) != 1 || models[0] != want {
+			t.Errorf("%s: models = %+v", name, models)
+		}
+		if err := p.Health(context.Background()); err != nil {
+			t.Errorf("%s: health: %v", name, err)
+		}
+	}
+	if _, err := llm.NewProvider("bard", srv.URL, ""); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
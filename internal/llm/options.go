@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options are the generation settings sent with each request. Unset
+// fields are left out so the server's, or the model's, defaults apply.
+type Options struct {
+	Temperature   *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty" yaml:"top_p,omitempty"`
+	NumCtx        *int     `json:"num_ctx,omitempty" yaml:"num_ctx,omitempty"`
+	Seed          *int     `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty" yaml:"stop,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty" yaml:"repeat_penalty,omitempty"`
+	// KeepAlive is how long Ollama keeps the model loaded after a request,
+	// e.g. "10m" or "-1" for ever. It is sent beside options, not in them.
+	KeepAlive string `json:"-" yaml:"keep_alive,omitempty"`
+}
+
+// IsZero reports whether no option is set.
+func (o Options) IsZero() bool {
+	return o.Temperature == nil && o.TopP == nil && o.NumCtx == nil && o.Seed == nil &&
+		len(o.Stop) == 0 && o.RepeatPenalty == nil && o.KeepAlive == ""
+}
+
+// Merge returns o with every option set in override replacing its own.
+func (o Options) Merge(override Options) Options {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.NumCtx != nil {
+		o.NumCtx = override.NumCtx
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	if len(override.Stop) > 0 {
+		o.Stop = override.Stop
+	}
+	if override.RepeatPenalty != nil {
+		o.RepeatPenalty = override.RepeatPenalty
+	}
+	if override.KeepAlive != "" {
+		o.KeepAlive = override.KeepAlive
+	}
+	return o
+}
+
+// optionFields maps option names, as used in the config file, to parsers
+// that set them. An empty value clears the option.
+var optionFields = map[string]func(o *Options, v string) error{
+	"temperature":    func(o *Options, v string) error { return setFloat(&o.Temperature, v) },
+	"top_p":          func(o *Options, v string) error { return setFloat(&o.TopP, v) },
+	"num_ctx":        func(o *Options, v string) error { return setInt(&o.NumCtx, v) },
+	"seed":           func(o *Options, v string) error { return setInt(&o.Seed, v) },
+	"repeat_penalty": func(o *Options, v string) error { return setFloat(&o.RepeatPenalty, v) },
+	"keep_alive":     func(o *Options, v string) error { o.KeepAlive = v; return nil },
+	"stop": func(o *Options, v string) error {
+		o.Stop = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				o.Stop = append(o.Stop, s)
+			}
+		}
+		return nil
+	},
+}
+
+// OptionNames lists the names accepted by Set.
+func OptionNames() []string {
+	names := make([]string, 0, len(optionFields))
+	for name := range optionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set changes the option called name, parsing value as the option's type.
+// An empty value resets the option to the server default; stop takes a
+// comma-separated list.
+func (o *Options) Set(name, value string) error {
+	set, ok := optionFields[name]
+	if !ok {
+		return fmt.Errorf("unknown option %q (want one of %s)", name, strings.Join(OptionNames(), ", "))
+	}
+	if err := set(o, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func setFloat(dst **float64, v string) error {
+	if v == "" {
+		*dst = nil
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", v)
+	}
+	*dst = &f
+	return nil
+}
+
+func setInt(dst **int, v string) error {
+	if v == "" {
+		*dst = nil
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", v)
+	}
+	*dst = &n
+	return nil
+}
+
+// String summarises the options that are set, e.g.
+// "temp=0.7 ctx=8192 seed=42", for the status bar.
+func (o Options) String() string {
+	var parts []string
+	add := func(label string, v any) { parts = append(parts, fmt.Sprintf("%s=%v", label, v)) }
+	if o.Temperature != nil {
+		add("temp", *o.Temperature)
+	}
+	if o.TopP != nil {
+		add("top_p", *o.TopP)
+	}
+	if o.NumCtx != nil {
+		add("ctx", *o.NumCtx)
+	}
+	if o.Seed != nil {
+		add("seed", *o.Seed)
+	}
+	if o.RepeatPenalty != nil {
+		add("repeat", *o.RepeatPenalty)
+	}
+	if len(o.Stop) > 0 {
+		add("stop", strconv.Quote(strings.Join(o.Stop, ",")))
+	}
+	if o.KeepAlive != "" {
+		add("keep_alive", o.KeepAlive)
+	}
+	return strings.Join(parts, " ")
+}
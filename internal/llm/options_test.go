@@ -0,0 +1,69 @@
+package llm_test
+
+import (
+	"clai/internal/llm"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptionsSet(t *testing.T) {
+	var o llm.Options
+	for name, value := range map[string]string{
+		"temperature": "0.2", "num_ctx": "8192", "seed": "42", "stop": "</s>, User:", "keep_alive": "10m",
+	} {
+		if err := o.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := o.String(); got != `temp=0.2 ctx=8192 seed=42 stop="</s>,User:" keep_alive=10m` {
+		t.Errorf("String() = %s", got)
+	}
+	if err := o.Set("temperature", ""); err != nil || o.Temperature != nil {
+		t.Errorf("empty value should reset the option, got %v %v", o.Temperature, err)
+	}
+	if err := o.Set("seed", "abc"); err == nil {
+		t.Error("expected an error for a non-integer seed")
+	}
+	if err := o.Set("top_k", "40"); err == nil {
+		t.Error("expected an error for an unknown option")
+	}
+}
+
+func TestClientSendsOptions(t *testing.T) {
+	var got []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"ok"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	client := llm.NewClient(srv.URL, "test", "")
+	if _, err := client.SendMessageWithTools(context.Background(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var o llm.Options
+	_ = o.Set("temperature", "0.7")
+	_ = o.Set("keep_alive", "-1")
+	client.SetOptions(o)
+	var zero llm.Options
+	_ = zero.Set("temperature", "0")
+	if _, err := client.WithOptions(zero).SendMessageWithTools(context.Background(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := got[0]["options"]; ok {
+		t.Errorf("unset options should be omitted, got %s", got[0]["options"])
+	}
+	if string(got[1]["options"]) != `{"temperature":0}` || string(got[1]["keep_alive"]) != `"-1"` {
+		t.Errorf("unexpected options %s keep_alive %s", got[1]["options"], got[1]["keep_alive"])
+	}
+	if *client.Options().Temperature != 0.7 {
+		t.Error("WithOptions must not change the client")
+	}
+}
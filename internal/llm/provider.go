@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Provider is a chat backend. It speaks one server's wire protocol; the
+// system prompt, tool registry and tool-call parsing stay in Client, so a
+// provider only translates requests and responses.
+type Provider interface {
+	// Chat sends req and returns the complete reply.
+	Chat(ctx context.Context, req Request) (Response, error)
+	// Stream sends req and calls onChunk with each piece of the reply as it
+	// arrives, finishing with a chunk that has Done set. It stops early
+	// when onChunk returns false. A stream that ends without a Done chunk
+	// and without an error was cut off, which the caller reports.
+	Stream(ctx context.Context, req Request, onChunk func(Response) bool) error
+	// ListModels returns the models the server can run.
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+	// Health reports whether the server is reachable.
+	Health(ctx context.Context) error
+}
+
+// ModelInfo describes a model offered by a provider. Fields a server does
+// not report are left zero.
+type ModelInfo struct {
+	Name string `json:"name"`
+	// Size is the size of the model files in bytes.
+	Size int64 `json:"size,omitempty"`
+	// ParameterSize is the parameter count as reported, e.g. "8.0B".
+	ParameterSize string `json:"parameter_size,omitempty"`
+	// Quantization is the quantization level, e.g. "Q4_K_M".
+	Quantization string    `json:"quantization,omitempty"`
+	Family       string    `json:"family,omitempty"`
+	ModifiedAt   time.Time `json:"modified_at,omitempty"`
+}
+
+// Provider names accepted by NewProvider.
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+)
+
+// NewProvider returns the provider called name talking to host. apiKey is
+// only used by OpenAI-compatible servers and may be empty.
+func NewProvider(name, host, apiKey string) (Provider, error) {
+	switch name {
+	case "", ProviderOllama:
+		return NewOllamaProvider(host), nil
+	case ProviderOpenAI:
+		return NewOpenAIProvider(host, apiKey), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want %s or %s)", name, ProviderOllama, ProviderOpenAI)
+	}
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ModelManager is implemented by providers that can download and remove
+// models on the server. Only Ollama can; OpenAI-compatible servers load
+// whatever they were started with.
+type ModelManager interface {
+	// Pull downloads a model, calling onProgress with each status update
+	// until it returns false or the pull finishes.
+	Pull(ctx context.Context, name string, onProgress func(PullProgress) bool) error
+	Delete(ctx context.Context, name string) error
+}
+
+// ErrModelManagementUnsupported is returned by PullModel and DeleteModel
+// when the provider cannot manage models.
+var ErrModelManagementUnsupported = errors.New("this provider cannot pull or delete models")
+
+// PullProgress is one status update of a model download. Digest, Total and
+// Completed are set while a layer is downloading; Total and Completed are
+// in bytes.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// Fraction is how much of the current layer has been downloaded, from 0
+// to 1, or 0 when the size is unknown.
+func (p PullProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// PullEvent is a value produced by PullModel. Like a StreamEvent sequence,
+// every pull ends with exactly one event that has Done or Err set, after
+// which the channel is closed.
+type PullEvent struct {
+	Progress PullProgress
+	Done     bool
+	Err      error
+}
+
+// PullModel downloads a model in the background and reports its progress.
+// Cancelling ctx aborts the download.
+func (c *Client) PullModel(ctx context.Context, name string) <-chan PullEvent {
+	events := make(chan PullEvent)
+	go func() {
+		defer close(events)
+		send := func(ev PullEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		manager, ok := c.provider.(ModelManager)
+		if !ok {
+			send(PullEvent{Err: ErrModelManagementUnsupported})
+			return
+		}
+		err := manager.Pull(ctx, name, func(p PullProgress) bool {
+			return send(PullEvent{Progress: p})
+		})
+		switch {
+		case ctx.Err() != nil:
+			select {
+			case events <- PullEvent{Err: ctx.Err()}:
+			default:
+			}
+		case err != nil:
+			send(PullEvent{Err: err})
+		default:
+			send(PullEvent{Done: true})
+		}
+	}()
+	return events
+}
+
+// DeleteModel removes a model from the server.
+func (c *Client) DeleteModel(ctx context.Context, name string) error {
+	manager, ok := c.provider.(ModelManager)
+	if !ok {
+		return ErrModelManagementUnsupported
+	}
+	return manager.Delete(ctx, name)
+}
+
+// pullChunk is one NDJSON line of an /api/pull response.
+type pullChunk struct {
+	PullProgress
+	Error string `json:"error,omitempty"`
+}
+
+func (p *OllamaProvider) Pull(ctx context.Context, name string, onProgress func(PullProgress) bool) error {
+	resp, err := p.postJSON(ctx, "/api/pull", map[string]any{"model": name, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	success := false
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var chunk pullChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return fmt.Errorf("error decoding pull status: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("pulling %s: %s", name, chunk.Error)
+		}
+		success = chunk.Status == "success"
+		if !onProgress(chunk.PullProgress) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading pull status: %w", err)
+	}
+	if !success {
+		return fmt.Errorf("pulling %s: stream ended before the download finished", name)
+	}
+	return nil
+}
+
+func (p *OllamaProvider) Delete(ctx context.Context, name string) error {
+	body, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, p.host+"/api/delete", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Ollama at %s: %w", p.host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package llm_test
+
+import (
+	"clai/internal/llm"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPullModelReportsProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/api/pull" || !strings.Contains(string(body), `"model":"llama3"`) {
+			t.Errorf("unexpected request %s %s", r.URL.Path, body)
+		}
+		for _, line := range []string{
+			`{"status":"pulling manifest"}`,
+			`{"status":"pulling abc","digest":"sha256:abc","total":200,"completed":50}`,
+			`{"status":"pulling abc","digest":"sha256:abc","total":200,"completed":200}`,
+			`{"status":"verifying sha256 digest"}`,
+			`{"status":"success"}`,
+		} {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	defer srv.Close()
+
+	var events []llm.PullEvent
+	for ev := range llm.NewClient(srv.URL, "test", "").PullModel(context.Background(), "llama3") {
+		events = append(events, ev)
+	}
+	if len(events) != 6 || !events[5].Done {
+		t.Fatalf("expected five updates then done, got %+v", events)
+	}
+	p := events[1].Progress
+	if p.Digest != "sha256:abc" || p.Total != 200 || p.Completed != 50 || p.Fraction() != 0.25 {
+		t.Errorf("unexpected progress %+v", p)
+	}
+}
+
+func TestPullModelError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+	}))
+	defer srv.Close()
+
+	var last llm.PullEvent
+	for ev := range llm.NewClient(srv.URL, "test", "").PullModel(context.Background(), "nope") {
+		last = ev
+	}
+	if last.Err == nil || !strings.Contains(last.Err.Error(), "file does not exist") {
+		t.Errorf("expected the server error, got %+v", last)
+	}
+}
+
+func TestDeleteModel(t *testing.T) {
+	var deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/delete" {
+			http.NotFound(w, r)
+			return
+		}
+		if !strings.Contains(string(body), `"llama3"`) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"error":"model not found"}`)
+			return
+		}
+		deleted = string(body)
+	}))
+	defer srv.Close()
+
+	client := llm.NewClient(srv.URL, "test", "")
+	if err := client.DeleteModel(context.Background(), "llama3"); err != nil || deleted == "" {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := client.DeleteModel(context.Background(), "other"); err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	client.SetProvider(llm.NewOpenAIProvider(srv.URL, ""))
+	if err := client.DeleteModel(context.Background(), "llama3"); !errors.Is(err, llm.ErrModelManagementUnsupported) {
+		t.Errorf("expected unsupported, got %v", err)
+	}
+}
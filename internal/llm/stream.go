@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// StreamEventType identifies the kind of value carried by a StreamEvent.
+type StreamEventType int
+
+const (
+	// EventContent carries a piece of assistant text in Content.
+	EventContent StreamEventType = iota
+	// EventToolCall carries one or more tool calls requested by the model.
+	EventToolCall
+	// EventDone is the terminal success event. Response holds the full
+	// assistant message assembled from every chunk.
+	EventDone
+	// EventError is the terminal failure event. Err holds the cause.
+	EventError
+)
+
+func (t StreamEventType) String() string {
+	switch t {
+	case EventContent:
+		return "content"
+	case EventToolCall:
+		return "tool_call"
+	case EventDone:
+		return "done"
+	case EventError:
+		return "error"
+	default:
+		return fmt.Sprintf("StreamEventType(%d)", int(t))
+	}
+}
+
+// StreamEvent is a single typed value produced by SendMessageStream.
+// Every stream ends with exactly one EventDone or EventError, after which
+// the channel is closed.
+type StreamEvent struct {
+	Type      StreamEventType
+	Content   string
+	ToolCalls []ToolCall
+	Response  Response
+	Err       error
+}
+
+// errStreamEnded is reported when the server closes the stream before the
+// reply is complete.
+var errStreamEnded = errors.New("stream ended before the model finished responding")
+
+// sendEvent delivers ev unless ctx is cancelled first, so an abandoned
+// stream never blocks its goroutine forever. Once ctx is done only a
+// terminal error is still offered, and only to a receiver already waiting.
+func sendEvent(ctx context.Context, events chan<- StreamEvent, ev StreamEvent) bool {
+	if ctx.Err() != nil {
+		if ev.Type != EventError {
+			return false
+		}
+		select {
+		case events <- ev:
+			return true
+		default:
+			return false
+		}
+	}
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readStream runs a streaming request through the provider and turns the
+// chunks into events, closing the channel when it is done. Content passes
+// through the tool-call filter so calls the model writes as text are
+// reported as calls rather than shown.
+func (c *Client) readStream(ctx context.Context, req Request, events chan<- StreamEvent) {
+	defer close(events)
+	send := func(ev StreamEvent) bool { return sendEvent(ctx, events, ev) }
+
+	filter := c.toolCallFilter()
+	var final Response
+	start := time.Now()
+	var firstToken time.Time
+	err := c.provider.Stream(ctx, req, func(chunk Response) bool {
+		if firstToken.IsZero() && (chunk.Message.Content != "" || len(chunk.Message.ToolCalls) > 0) {
+			firstToken = time.Now()
+		}
+		if visible := filter.Write(chunk.Message.Content); visible != "" {
+			final.Message.Content += visible
+			if !send(StreamEvent{Type: EventContent, Content: visible}) {
+				return false
+			}
+		}
+		if len(chunk.Message.ToolCalls) > 0 {
+			final.Message.ToolCalls = append(final.Message.ToolCalls, chunk.Message.ToolCalls...)
+			if !send(StreamEvent{Type: EventToolCall, ToolCalls: chunk.Message.ToolCalls}) {
+				return false
+			}
+		}
+		if !chunk.Done {
+			return true
+		}
+		if visible := filter.Flush(); visible != "" {
+			final.Message.Content += visible
+			if !send(StreamEvent{Type: EventContent, Content: visible}) {
+				return false
+			}
+		}
+		if len(final.Message.ToolCalls) == 0 && len(filter.calls) > 0 {
+			final.Message.ToolCalls = filter.calls
+			if !send(StreamEvent{Type: EventToolCall, ToolCalls: filter.calls}) {
+				return false
+			}
+		}
+		final.Message.Content = strings.TrimSpace(final.Message.Content)
+		final.Message.Role = "assistant"
+		final.Done = true
+		final.Metrics = completeMetrics(chunk.Metrics, start, firstToken, time.Now())
+		prettyResp, _ := json.MarshalIndent(final, "", "  ")
+		log.Printf("[LLM-RESP-STREAM] %s", string(prettyResp))
+		send(StreamEvent{Type: EventDone, Response: final})
+		return false
+	})
+	switch {
+	case final.Done:
+	case ctx.Err() != nil:
+		send(StreamEvent{Type: EventError, Err: ctx.Err()})
+	case err != nil:
+		send(StreamEvent{Type: EventError, Err: err})
+	default:
+		send(StreamEvent{Type: EventError, Err: errStreamEnded})
+	}
+}
+
+// completeMetrics adds the client-side timings to those the server
+// reported, estimating any durations it did not report.
+func completeMetrics(m Metrics, start, firstToken, end time.Time) Metrics {
+	if !firstToken.IsZero() {
+		m.TimeToFirstToken = firstToken.Sub(start)
+		if m.EvalDuration == 0 && m.EvalCount > 0 {
+			m.EvalDuration = end.Sub(firstToken)
+		}
+	}
+	if m.TotalDuration == 0 {
+		m.TotalDuration = end.Sub(start)
+	}
+	return m
+}
@@ -0,0 +1,149 @@
+package llm_test
+
+import (
+	"clai/internal/llm"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func collect(events <-chan llm.StreamEvent) []llm.StreamEvent {
+	var out []llm.StreamEvent
+	for ev := range events {
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestSendMessageStreamDeliversEveryChunk(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, word := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, `{"message":{"role":"assistant","content":%q},"done":false}`+"\n", word)
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer srv.Close()
+
+	events := collect(llm.NewClient(srv.URL, "test", "").SendMessageStream(context.Background(), nil))
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d: %+v", len(events), events)
+	}
+	var content string
+	for _, ev := range events[:3] {
+		if ev.Type != llm.EventContent {
+			t.Fatalf("expected content event, got %s", ev.Type)
+		}
+		content += ev.Content
+	}
+	if content != "Hello, world" {
+		t.Errorf("unexpected content %q", content)
+	}
+	last := events[3]
+	if last.Type != llm.EventDone || last.Response.Message.Content != "Hello, world" {
+		t.Errorf("unexpected terminal event %+v", last)
+	}
+}
+
+func TestSendMessageStreamEndsWithSingleError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, `{"error":"model \"test\" not found"}`)
+	}))
+	defer srv.Close()
+
+	events := collect(llm.NewClient(srv.URL, "test", "").SendMessageStream(context.Background(), nil))
+	if len(events) != 1 || events[0].Type != llm.EventError || events[0].Err == nil {
+		t.Fatalf("expected a single error event, got %+v", events)
+	}
+}
+
+func TestSendMessageStreamTruncatedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"partial"},"done":false}`)
+	}))
+	defer srv.Close()
+
+	events := collect(llm.NewClient(srv.URL, "test", "").SendMessageStream(context.Background(), nil))
+	if len(events) != 2 || events[1].Type != llm.EventError {
+		t.Fatalf("expected content then error, got %+v", events)
+	}
+}
+
+func TestSendMessageStreamCancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"slow"},"done":false}`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := llm.NewClient(srv.URL, "test", "").SendMessageStream(ctx, nil)
+	if ev := <-events; ev.Type != llm.EventContent {
+		t.Fatalf("expected first chunk, got %+v", ev)
+	}
+	cancel()
+	select {
+	case ev, ok := <-events:
+		if ok && (ev.Type != llm.EventError || !errors.Is(ev.Err, context.Canceled)) {
+			t.Fatalf("expected cancellation error, got %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not stop after cancel")
+	}
+}
+
+func TestSendMessageStreamTextToolCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, part := range []string{`{"tool_calls": [{"name": "echo", `, `"parameters": {"message": "hi"}}]}`} {
+			fmt.Fprintf(w, `{"message":{"role":"assistant","content":%q},"done":false}`+"\n", part)
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer srv.Close()
+
+	events := collect(llm.NewClient(srv.URL, "test", "").SendMessageStream(context.Background(), nil))
+	if len(events) != 2 || events[0].Type != llm.EventToolCall || events[1].Type != llm.EventDone {
+		t.Fatalf("expected tool call then done, got %+v", events)
+	}
+	resp := events[1].Response
+	if resp.Message.Content != "" || len(resp.Message.ToolCalls) != 1 || resp.Message.ToolCalls[0].Name != "echo" {
+		t.Errorf("unexpected final response %+v", resp)
+	}
+}
+
+func TestSendMessageStreamMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"hi"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"total_duration":3000000000,"prompt_eval_count":26,"eval_count":100,"eval_duration":2000000000}`)
+	}))
+	defer srv.Close()
+
+	events := collect(llm.NewClient(srv.URL, "test", "").SendMessageStream(context.Background(), nil))
+	m := events[len(events)-1].Response.Metrics
+	if m.PromptEvalCount != 26 || m.EvalCount != 100 || m.TotalDuration != 3*time.Second {
+		t.Errorf("server metrics not decoded: %+v", m)
+	}
+	if m.TokensPerSecond() != 50 {
+		t.Errorf("tokens/s = %v", m.TokensPerSecond())
+	}
+	if m.TimeToFirstToken <= 0 {
+		t.Error("time to first token not measured")
+	}
+
+	var u llm.Usage
+	u.Add(m)
+	u.Add(llm.Metrics{PromptEvalCount: 4, EvalCount: 100, EvalDuration: 2 * time.Second})
+	if u.String() != "30→200 tok, 50.0 tok/s" || u.TimeToFirstToken != m.TimeToFirstToken {
+		t.Errorf("usage = %s, ttft %v", u, u.TimeToFirstToken)
+	}
+}
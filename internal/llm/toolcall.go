@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ToolCall is a single tool invocation requested by the model.
+//
+// Models describe calls in more than one shape. Ollama's native tool API
+// returns {"function":{"name":...,"arguments":{...}}}, OpenAI-style servers
+// send arguments as a JSON-encoded string, and the text protocol in
+// defaultSystemPrompt uses {"name":...,"parameters":{...}}. All of them
+// decode into the same value; encoding always produces the Ollama shape so
+// calls can be echoed back in the conversation history.
+//
+// Arguments that are not valid JSON, as small models sometimes send, do not
+// fail decoding: they are kept in InvalidArguments and the call is answered
+// with the error, so the model can correct itself.
+type ToolCall struct {
+	Name       string
+	Parameters json.RawMessage
+	// InvalidArguments holds arguments the model sent that were not valid
+	// JSON; Parameters is then empty.
+	InvalidArguments string
+}
+
+type toolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+func (tc ToolCall) MarshalJSON() ([]byte, error) {
+	args := tc.Parameters
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	return json.Marshal(struct {
+		Function toolCallFunction `json:"function"`
+	}{toolCallFunction{Name: tc.Name, Arguments: args}})
+}
+
+func (tc *ToolCall) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name       string            `json:"name"`
+		Parameters json.RawMessage   `json:"parameters"`
+		Arguments  json.RawMessage   `json:"arguments"`
+		Function   *toolCallFunction `json:"function"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	name, args := raw.Name, raw.Parameters
+	if len(args) == 0 {
+		args = raw.Arguments
+	}
+	if raw.Function != nil {
+		name, args = raw.Function.Name, raw.Function.Arguments
+	}
+	if name == "" {
+		return fmt.Errorf("tool call has no name: %s", data)
+	}
+
+	// OpenAI-compatible servers encode the arguments object as a string.
+	var encoded string
+	if json.Unmarshal(args, &encoded) == nil {
+		args = json.RawMessage(encoded)
+	}
+	if len(bytes.TrimSpace(args)) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	tc.Name = name
+	tc.Parameters, tc.InvalidArguments = args, ""
+	if !json.Valid(args) {
+		tc.Parameters, tc.InvalidArguments = nil, string(args)
+	}
+	return nil
+}
+
+// ArgumentsError describes what is wrong with the call's arguments, or
+// returns nil if they are valid JSON.
+func (tc ToolCall) ArgumentsError() error {
+	if tc.InvalidArguments == "" {
+		return nil
+	}
+	var v any
+	err := json.Unmarshal([]byte(tc.InvalidArguments), &v)
+	return fmt.Errorf("invalid arguments: %v: %s", err, tc.InvalidArguments)
+}
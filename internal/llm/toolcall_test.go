@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToolCallUnmarshalShapes(t *testing.T) {
+	cases := map[string]string{
+		"ollama":      `{"function":{"name":"calculator","arguments":{"expression":"1+1"}}}`,
+		"openai":      `{"id":"call_1","type":"function","function":{"name":"calculator","arguments":"{\"expression\":\"1+1\"}"}}`,
+		"text prompt": `{"name":"calculator","parameters":{"expression":"1+1"}}`,
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			var tc ToolCall
+			if err := json.Unmarshal([]byte(in), &tc); err != nil {
+				t.Fatal(err)
+			}
+			if tc.Name != "calculator" {
+				t.Errorf("name = %q", tc.Name)
+			}
+			var params struct{ Expression string }
+			if err := json.Unmarshal(tc.Parameters, &params); err != nil || params.Expression != "1+1" {
+				t.Errorf("parameters = %s (%v)", tc.Parameters, err)
+			}
+		})
+	}
+}
+
+func TestToolCallMissingArguments(t *testing.T) {
+	var tc ToolCall
+	if err := json.Unmarshal([]byte(`{"function":{"name":"echo"}}`), &tc); err != nil {
+		t.Fatal(err)
+	}
+	if string(tc.Parameters) != "{}" {
+		t.Errorf("parameters = %s, want {}", tc.Parameters)
+	}
+	if err := json.Unmarshal([]byte(`{"function":{"arguments":{}}}`), &tc); err == nil {
+		t.Error("expected an error for a nameless call")
+	}
+}
+
+func TestToolCallRoundTrip(t *testing.T) {
+	in := ToolCall{Name: "echo", Parameters: json.RawMessage(`{"message":"hi"}`)}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"function":{"name":"echo","arguments":{"message":"hi"}}}` {
+		t.Errorf("marshalled %s", data)
+	}
+	var out ToolCall
+	if err := json.Unmarshal(data, &out); err != nil || out.Name != in.Name || string(out.Parameters) != string(in.Parameters) {
+		t.Errorf("round trip = %+v (%v)", out, err)
+	}
+}
+
+func TestToolCallInvalidArguments(t *testing.T) {
+	// OpenAI-style arguments cut off mid-object, as a small model may send.
+	in := `{"id":"call_1","type":"function","function":{"name":"calculator","arguments":"{\"expression\":\"2+"}}`
+	var tc ToolCall
+	if err := json.Unmarshal([]byte(in), &tc); err != nil {
+		t.Fatalf("malformed arguments should not fail decoding: %v", err)
+	}
+	if tc.Name != "calculator" || tc.InvalidArguments != `{"expression":"2+` || tc.Parameters != nil {
+		t.Fatalf("unexpected call %+v", tc)
+	}
+	if data, err := json.Marshal(tc); err != nil || string(data) != `{"function":{"name":"calculator","arguments":{}}}` {
+		t.Errorf("marshalled %s (%v)", data, err)
+	}
+
+	client := NewClient("http://unused", "test", "")
+	msg := client.ExecuteToolCall(context.Background(), tc)
+	if msg.Role != "tool" || msg.ToolName != "calculator" || !strings.Contains(msg.Content, "invalid arguments: unexpected end of JSON input") {
+		t.Errorf("unexpected result %+v", msg)
+	}
+}
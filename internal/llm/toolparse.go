@@ -0,0 +1,263 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Many local models ignore the native tools API and follow
+// defaultSystemPrompt literally, writing {"tool_calls": [...]} into the
+// message text, sometimes wrapped in a ```json fence or surrounded by prose.
+// The code below recognises those blocks, turns them into ToolCalls and
+// strips them from the text the user sees. It works incrementally so a
+// streamed reply can be shown while it arrives: text is released as soon as
+// it cannot be the start of a tool call, and only a possible call is held
+// back until it is complete.
+
+// ParseToolCalls extracts tool calls written as JSON in content and returns
+// them together with the remaining text.
+func ParseToolCalls(content string) ([]ToolCall, string) {
+	var f toolCallFilter
+	text := f.Write(content) + f.Flush()
+	return f.calls, strings.TrimSpace(text)
+}
+
+// toolCallFilter is the streaming form of ParseToolCalls. Write returns the
+// part of the input that is safe to display; Flush releases whatever is
+// still held once the stream ends.
+type toolCallFilter struct {
+	pending string
+	calls   []ToolCall
+	// isTool, when set, rejects calls naming tools that don't exist so an
+	// ordinary JSON answer like {"name": "Ada", "parameters": ...} stays visible.
+	isTool func(name string) bool
+}
+
+func (f *toolCallFilter) Write(chunk string) string {
+	f.pending += chunk
+	return f.drain(false)
+}
+
+func (f *toolCallFilter) Flush() string {
+	return f.drain(true)
+}
+
+func (f *toolCallFilter) drain(final bool) string {
+	var out strings.Builder
+	for f.pending != "" {
+		i := strings.IndexAny(f.pending, "{`")
+		if i < 0 {
+			out.WriteString(f.pending)
+			f.pending = ""
+			break
+		}
+		out.WriteString(f.pending[:i])
+		f.pending = f.pending[i:]
+
+		res, n, calls := scanCandidate(f.pending, final)
+		if res == scanMore {
+			break
+		}
+		if res == scanCall && f.known(calls) {
+			f.calls = append(f.calls, calls...)
+		} else {
+			out.WriteString(f.pending[:n])
+		}
+		f.pending = f.pending[n:]
+	}
+	return out.String()
+}
+
+func (f *toolCallFilter) known(calls []ToolCall) bool {
+	if f.isTool == nil {
+		return true
+	}
+	for _, call := range calls {
+		if !f.isTool(call.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+type scanResult int
+
+const (
+	// scanText means the first n bytes are ordinary text.
+	scanText scanResult = iota
+	// scanMore means more input is needed to decide.
+	scanMore
+	// scanCall means the first n bytes are a tool-call block.
+	scanCall
+)
+
+// scanCandidate classifies s, which begins with '{' or '`'. When final is
+// set there is no more input, so it never returns scanMore.
+func scanCandidate(s string, final bool) (scanResult, int, []ToolCall) {
+	if s[0] == '`' {
+		return scanFence(s, final)
+	}
+	return scanObject(s, final)
+}
+
+// toolCallKeys are the first keys a tool-call object can start with.
+var toolCallKeys = []string{`"tool_calls"`, `"name"`, `"function"`}
+
+func scanObject(s string, final bool) (scanResult, int, []ToolCall) {
+	rest := strings.TrimLeft(s[1:], " \t\r\n")
+	if rest == "" {
+		if final {
+			return scanText, 1, nil
+		}
+		return scanMore, 0, nil
+	}
+
+	candidate := false
+	for _, key := range toolCallKeys {
+		if strings.HasPrefix(rest, key) {
+			candidate = true
+			break
+		}
+		if strings.HasPrefix(key, rest) && !final {
+			return scanMore, 0, nil
+		}
+	}
+	if !candidate {
+		return scanText, 1, nil
+	}
+
+	end := matchBrace(s)
+	if end < 0 {
+		if final {
+			return scanText, 1, nil
+		}
+		return scanMore, 0, nil
+	}
+	calls, ok := decodeToolCalls(s[:end])
+	if !ok {
+		return scanText, end, nil
+	}
+	return scanCall, end, calls
+}
+
+func scanFence(s string, final bool) (scanResult, int, []ToolCall) {
+	if !strings.HasPrefix(s, "```") {
+		ticks := len(s) - len(strings.TrimLeft(s, "`"))
+		if ticks == len(s) && !final {
+			return scanMore, 0, nil
+		}
+		return scanText, ticks, nil
+	}
+
+	nl := strings.IndexByte(s, '\n')
+	if nl < 0 {
+		if final {
+			return scanText, len(s), nil
+		}
+		return scanMore, 0, nil
+	}
+	if lang := strings.TrimSpace(strings.Trim(s[:nl], "`")); lang != "" && lang != "json" {
+		return scanText, nl + 1, nil
+	}
+
+	body := s[nl+1:]
+	trimmed := strings.TrimLeft(body, " \t\r\n")
+	if trimmed == "" {
+		if final {
+			return scanText, nl + 1, nil
+		}
+		return scanMore, 0, nil
+	}
+	if trimmed[0] != '{' {
+		return scanText, nl + 1, nil
+	}
+
+	start := nl + 1 + len(body) - len(trimmed)
+	res, n, calls := scanObject(s[start:], final)
+	switch res {
+	case scanMore:
+		return scanMore, 0, nil
+	case scanText:
+		return scanText, nl + 1, nil
+	}
+
+	objEnd := start + n
+	after := strings.TrimLeft(s[objEnd:], " \t\r\n")
+	switch {
+	case strings.HasPrefix(after, "```"):
+		end := len(s) - len(after) + 3
+		if strings.HasPrefix(s[end:], "\n") {
+			end++
+		}
+		return scanCall, end, calls
+	case after == "" || strings.Trim(after, "`") == "":
+		if final {
+			return scanCall, len(s), calls
+		}
+		return scanMore, 0, nil
+	default:
+		return scanText, nl + 1, nil
+	}
+}
+
+// matchBrace returns the index just past the brace closing the object that
+// starts s, or -1 if the object is not complete yet.
+func matchBrace(s string) int {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// decodeToolCalls accepts {"tool_calls":[...]} or a single call object. A
+// lone call must carry arguments so ordinary JSON with a "name" key is not
+// mistaken for one.
+func decodeToolCalls(obj string) ([]ToolCall, bool) {
+	var wrapper struct {
+		ToolCalls []ToolCall `json:"tool_calls"`
+	}
+	if err := json.Unmarshal([]byte(obj), &wrapper); err == nil && len(wrapper.ToolCalls) > 0 {
+		return wrapper.ToolCalls, true
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(obj), &keys); err != nil {
+		return nil, false
+	}
+	_, hasFunction := keys["function"]
+	_, hasParams := keys["parameters"]
+	_, hasArgs := keys["arguments"]
+	if !hasFunction && !hasParams && !hasArgs {
+		return nil, false
+	}
+	var call ToolCall
+	if err := json.Unmarshal([]byte(obj), &call); err != nil {
+		return nil, false
+	}
+	return []ToolCall{call}, true
+}
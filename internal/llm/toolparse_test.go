@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseToolCalls(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		calls    []string
+		wantText string
+	}{
+		{
+			name:    "wrapper object",
+			content: `{"tool_calls": [{"name": "calculator", "parameters": {"expression": "2+2"}}]}`,
+			calls:   []string{"calculator"},
+		},
+		{
+			name:     "fenced with prose",
+			content:  "Let me work that out.\n\n```json\n{\"tool_calls\": [{\"name\": \"echo\", \"parameters\": {\"message\": \"}\"}}]}\n```\nOne moment.",
+			calls:    []string{"echo"},
+			wantText: "Let me work that out.\n\nOne moment.",
+		},
+		{
+			name:     "single call with function shape",
+			content:  `Sure: {"function": {"name": "echo", "arguments": {"message": "hi"}}}`,
+			calls:    []string{"echo"},
+			wantText: "Sure:",
+		},
+		{
+			name:     "ordinary json stays visible",
+			content:  `Here is a user: {"name": "Ada", "age": 36}`,
+			wantText: `Here is a user: {"name": "Ada", "age": 36}`,
+		},
+		{
+			name:     "code block stays visible",
+			content:  "```go\nfunc main() {\n\tfmt.Println(\"{}\")\n}\n```",
+			wantText: "```go\nfunc main() {\n\tfmt.Println(\"{}\")\n}\n```",
+		},
+		{
+			name:     "unterminated call is shown",
+			content:  `{"tool_calls": [{"name": "echo"`,
+			wantText: `{"tool_calls": [{"name": "echo"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls, text := ParseToolCalls(tc.content)
+			var names []string
+			for _, c := range calls {
+				names = append(names, c.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.calls, ",") {
+				t.Errorf("calls = %v, want %v", names, tc.calls)
+			}
+			if text != tc.wantText {
+				t.Errorf("text = %q, want %q", text, tc.wantText)
+			}
+		})
+	}
+}
+
+func TestToolCallFilterStreaming(t *testing.T) {
+	content := "Checking.\n```json\n{\"tool_calls\": [{\"name\": \"calculator\", \"parameters\": {\"expression\": \"6*7\"}}]}\n```\n"
+	f := toolCallFilter{}
+	var shown strings.Builder
+	for _, r := range content {
+		out := f.Write(string(r))
+		if strings.Contains(out, "tool_calls") {
+			t.Fatalf("raw tool call leaked while streaming: %q", out)
+		}
+		shown.WriteString(out)
+	}
+	shown.WriteString(f.Flush())
+	if strings.TrimSpace(shown.String()) != "Checking." {
+		t.Errorf("shown = %q", shown.String())
+	}
+	if len(f.calls) != 1 || f.calls[0].Name != "calculator" || string(f.calls[0].Parameters) != `{"expression": "6*7"}` {
+		t.Errorf("calls = %+v", f.calls)
+	}
+}
+
+func TestToolCallFilterUnknownTool(t *testing.T) {
+	f := toolCallFilter{isTool: func(name string) bool { return name == "echo" }}
+	in := `{"name": "launch_rockets", "parameters": {}}`
+	if out := f.Write(in) + f.Flush(); out != in || len(f.calls) != 0 {
+		t.Errorf("unknown tool should stay visible, got %q and %+v", out, f.calls)
+	}
+}
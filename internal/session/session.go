@@ -0,0 +1,335 @@
+// Package session stores conversations on disk so they survive restarts.
+//
+// Each session is a JSONL file named <id>.jsonl. The first line is a
+// "session" record holding the metadata; every following line is a
+// "message" record appended as the conversation goes on, so a crash loses
+// at most the reply that was still streaming. "info" records replace the
+// metadata when the model or system prompt changes mid-conversation, and
+// "rewind" records drop messages that were regenerated.
+package session
+
+import (
+	"bufio"
+	"clai/internal/llm"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no session matches the requested ID.
+var ErrNotFound = errors.New("session not found")
+
+// Info is the metadata written at the top of every session file.
+type Info struct {
+	ID           string    `json:"id"`
+	Model        string    `json:"model"`
+	Host         string    `json:"host"`
+	SystemPrompt string    `json:"system_prompt"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Session is a conversation loaded from or being written to disk.
+type Session struct {
+	Info
+	UpdatedAt time.Time
+	Messages  []llm.Message
+}
+
+// Summary describes a stored session without its full transcript.
+type Summary struct {
+	Info
+	UpdatedAt    time.Time
+	MessageCount int
+	// Title is the first user prompt, used to label the session in lists.
+	Title string
+}
+
+const (
+	recordSession = "session"
+	recordMessage = "message"
+	recordRewind  = "rewind"
+	recordInfo    = "info"
+)
+
+// record is one line of a session file.
+type record struct {
+	Type      string       `json:"type"`
+	Time      time.Time    `json:"time"`
+	Session   *Info        `json:"session,omitempty"`
+	Message   *llm.Message `json:"message,omitempty"`
+	Truncated bool         `json:"truncated,omitempty"`
+	// Count is the number of messages kept by a "rewind" record.
+	Count int `json:"count,omitempty"`
+}
+
+// Store reads and writes sessions in a single directory.
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// DefaultDir is $XDG_DATA_HOME/clai/sessions, falling back to
+// ~/.local/share/clai/sessions.
+func DefaultDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("finding home directory: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "clai", "sessions"), nil
+}
+
+// NewID returns a sortable, reasonably unique session ID such as
+// 20260102-150405-a1b2c3.
+func NewID(now time.Time) string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+func (s *Store) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(s.Dir, id+".jsonl"), nil
+}
+
+// Create starts a new session file. A missing ID or CreatedAt is filled in.
+func (s *Store) Create(info Info) (*Session, error) {
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+	if info.ID == "" {
+		info.ID = NewID(info.CreatedAt)
+	}
+	path, err := s.path(info.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating session directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("creating session file: %w", err)
+	}
+	defer f.Close()
+	if err := writeRecord(f, record{Type: recordSession, Time: info.CreatedAt, Session: &info}); err != nil {
+		return nil, err
+	}
+	return &Session{Info: info, UpdatedAt: info.CreatedAt}, nil
+}
+
+// Append adds messages to the end of a session file.
+func (s *Store) Append(id string, msgs ...llm.Message) error {
+	f, err := s.openAppend(id)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	now := time.Now()
+	for i := range msgs {
+		msg := msgs[i]
+		if err := writeRecord(f, record{Type: recordMessage, Time: now, Message: &msg, Truncated: msg.Truncated}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewind records that the session now ends after its first n messages, as
+// when a reply is regenerated. The file keeps the dropped messages, but
+// Load no longer returns them.
+func (s *Store) Rewind(id string, n int) error {
+	f, err := s.openAppend(id)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeRecord(f, record{Type: recordRewind, Time: time.Now(), Count: n})
+}
+
+// UpdateInfo records new metadata for a session, such as a model or
+// system prompt switched to mid-conversation. Load returns the latest;
+// the ID and creation time stay those of the session.
+func (s *Store) UpdateInfo(id string, info Info) error {
+	f, err := s.openAppend(id)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeRecord(f, record{Type: recordInfo, Time: time.Now(), Session: &info})
+}
+
+func (s *Store) openAppend(id string) (*os.File, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("opening session file: %w", err)
+	}
+	return f, nil
+}
+
+func writeRecord(f *os.File, r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encoding session record: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing session record: %w", err)
+	}
+	return nil
+}
+
+// Load reads a whole session.
+func (s *Store) Load(id string) (*Session, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return sess, err
+}
+
+// ReadFile reads a session file at any path, such as one written by
+// WriteFile.
+func ReadFile(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("opening session file: %w", err)
+	}
+	defer f.Close()
+
+	var sess *Session
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filepath.Base(path), line, err)
+		}
+		switch {
+		case r.Type == recordSession && r.Session != nil:
+			sess = &Session{Info: *r.Session, UpdatedAt: r.Time}
+		case r.Type == recordMessage && r.Message != nil && sess != nil:
+			msg := *r.Message
+			msg.Truncated = r.Truncated
+			sess.Messages = append(sess.Messages, msg)
+			sess.UpdatedAt = r.Time
+		case r.Type == recordRewind && sess != nil && r.Count <= len(sess.Messages):
+			sess.Messages = sess.Messages[:r.Count]
+			sess.UpdatedAt = r.Time
+		case r.Type == recordInfo && r.Session != nil && sess != nil:
+			info := *r.Session
+			info.ID, info.CreatedAt = sess.ID, sess.CreatedAt
+			sess.Info = info
+			sess.UpdatedAt = r.Time
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading session file: %w", err)
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("%s has no session header", filepath.Base(path))
+	}
+	return sess, nil
+}
+
+// WriteFile writes the whole session to path in the session file format,
+// replacing any file already there.
+func (sess *Session) WriteFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating session file: %w", err)
+	}
+	updated := sess.UpdatedAt
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	err = writeRecord(f, record{Type: recordSession, Time: sess.CreatedAt, Session: &sess.Info})
+	for i := 0; err == nil && i < len(sess.Messages); i++ {
+		msg := sess.Messages[i]
+		err = writeRecord(f, record{Type: recordMessage, Time: updated, Message: &msg, Truncated: msg.Truncated})
+	}
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing session file: %w", closeErr)
+	}
+	return err
+}
+
+// List summarises every stored session, most recently updated first.
+// Unreadable files are skipped.
+func (s *Store) List() ([]Summary, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading session directory: %w", err)
+	}
+	var out []Summary
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".jsonl")
+		if e.IsDir() || !ok {
+			continue
+		}
+		sess, err := s.Load(id)
+		if err != nil {
+			continue
+		}
+		out = append(out, sess.Summary())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out, nil
+}
+
+// Latest loads the most recently updated session.
+func (s *Store) Latest() (*Session, error) {
+	summaries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Load(summaries[0].ID)
+}
+
+func (s *Session) Summary() Summary {
+	sum := Summary{Info: s.Info, UpdatedAt: s.UpdatedAt, MessageCount: len(s.Messages)}
+	for _, m := range s.Messages {
+		if m.Role == "user" {
+			sum.Title = m.Content
+			break
+		}
+	}
+	return sum
+}
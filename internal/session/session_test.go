@@ -0,0 +1,152 @@
+package session
+
+import (
+	"clai/internal/llm"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	store := NewStore(t.TempDir())
+	sess, err := store.Create(Info{Model: "llama3", Host: "http://localhost:11434", SystemPrompt: "be brief"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []llm.Message{
+		{Role: "user", Content: "what is 2+2?"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{Name: "calculator", Parameters: json.RawMessage(`{"expression":"2+2"}`)}}},
+		{Role: "tool", ToolName: "calculator", Content: "4"},
+		{Role: "assistant", Content: "It is", Truncated: true},
+	}
+	if err := store.Append(sess.ID, msgs[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(sess.ID, msgs[2:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Model != "llama3" || loaded.SystemPrompt != "be brief" || loaded.CreatedAt.IsZero() {
+		t.Errorf("unexpected info %+v", loaded.Info)
+	}
+	if len(loaded.Messages) != len(msgs) {
+		t.Fatalf("got %d messages, want %d", len(loaded.Messages), len(msgs))
+	}
+	if loaded.Messages[2].ToolName != "calculator" || loaded.Messages[1].ToolCalls[0].Name != "calculator" {
+		t.Errorf("tool data lost: %+v", loaded.Messages)
+	}
+	if !loaded.Messages[3].Truncated {
+		t.Error("truncated flag lost")
+	}
+	if sum := loaded.Summary(); sum.Title != "what is 2+2?" || sum.MessageCount != 4 {
+		t.Errorf("unexpected summary %+v", sum)
+	}
+}
+
+func TestRewindAndWriteFile(t *testing.T) {
+	store := NewStore(t.TempDir())
+	sess, err := store.Create(Info{Model: "llama3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(sess.ID, llm.Message{Role: "user", Content: "hi"}, llm.Message{Role: "assistant", Content: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Rewind(sess.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(sess.ID, llm.Message{Role: "assistant", Content: "second"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Messages) != 2 || loaded.Messages[1].Content != "second" {
+		t.Fatalf("rewound reply still loaded: %+v", loaded.Messages)
+	}
+
+	path := filepath.Join(t.TempDir(), "copy.jsonl")
+	if err := loaded.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.ID != sess.ID || copied.Model != "llama3" || len(copied.Messages) != 2 {
+		t.Errorf("copy differs: %+v", copied)
+	}
+	if err := store.Rewind("missing", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateInfo(t *testing.T) {
+	store := NewStore(t.TempDir())
+	sess, err := store.Create(Info{Model: "llama3", SystemPrompt: "be brief"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(sess.ID, llm.Message{Role: "user", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateInfo(sess.ID, Info{ID: "other", Model: "qwen2.5", SystemPrompt: "be thorough"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Model != "qwen2.5" || loaded.SystemPrompt != "be thorough" {
+		t.Errorf("info not updated: %+v", loaded.Info)
+	}
+	if loaded.ID != sess.ID || !loaded.CreatedAt.Equal(sess.CreatedAt) || len(loaded.Messages) != 1 {
+		t.Errorf("session identity or messages lost: %+v", loaded)
+	}
+	if err := store.UpdateInfo("missing", Info{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreListAndLatest(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, err := store.Latest(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from empty store, got %v", err)
+	}
+	old, _ := store.Create(Info{CreatedAt: time.Now().Add(-time.Hour)})
+	recent, _ := store.Create(Info{})
+	if err := store.Append(recent.ID, llm.Message{Role: "user", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	if list[0].ID != recent.ID || list[1].ID != old.ID {
+		t.Errorf("sessions not ordered by recency: %v", list)
+	}
+	latest, err := store.Latest()
+	if err != nil || latest.ID != recent.ID {
+		t.Errorf("Latest = %v, %v", latest, err)
+	}
+}
+
+func TestStoreRejectsBadIDs(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for _, id := range []string{"", "../escape", "a/b", ".hidden"} {
+		if _, err := store.Load(id); err == nil {
+			t.Errorf("Load(%q) should fail", id)
+		}
+	}
+	if _, err := store.Load("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
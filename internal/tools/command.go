@@ -0,0 +1,383 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RunCommandParams struct {
+	Command string `json:"command" description:"Command line to run in the workspace root, e.g. go test ./... It is not run by a shell, so pipes, redirection, variables and globs do not work" required:"true"`
+	Timeout int    `json:"timeout,omitempty" description:"Seconds to allow the command, up to the configured limit"`
+}
+
+// CommandRules limit what run_command may run. Allow and Deny hold command
+// patterns such as "go test" or "rm -rf": the program, then words and
+// flags. Flags match in any order and grouping, so "rm -rf" also catches
+// "rm -fr" and "rm -r -f". The rules keep honest mistakes out; they are not
+// a sandbox, and a determined command line can get around them.
+type CommandRules struct {
+	// Allow, if not empty, lists the only commands that may run. A command
+	// matches when its first words are the pattern's words and it has the
+	// pattern's flags.
+	Allow []string
+	// Deny refuses commands even if Allow admits them. A command matches
+	// when it has the pattern's words in order, anywhere after the program,
+	// and its flags.
+	Deny []string
+	// Timeout is the longest a command may run before it is killed.
+	Timeout time.Duration
+	// MaxOutput caps the stdout and the stderr returned, in bytes each.
+	MaxOutput int
+}
+
+// DefaultDeniedCommands are refused unless the configuration says
+// otherwise: shells and wrappers that would run another command out of
+// sight of the rules, privilege escalation, recursive removal and pushing.
+var DefaultDeniedCommands = []string{
+	"sh", "bash", "zsh", "dash", "ksh", "fish", "csh", "tcsh",
+	"env", "xargs", "nohup", "nice", "timeout", "time", "watch", "setsid", "script",
+	"sudo", "su", "doas", "pkexec",
+	"find -exec", "find -execdir", "find -ok", "find -delete",
+	"rm -r", "rm -R", "rm --recursive",
+	"git push",
+}
+
+const (
+	defaultCommandTimeout = 2 * time.Minute
+	defaultCommandOutput  = 8 * 1024
+)
+
+var (
+	commandMu    sync.Mutex
+	commandRules = CommandRules{Deny: DefaultDeniedCommands, Timeout: defaultCommandTimeout, MaxOutput: defaultCommandOutput}
+)
+
+// SetCommandRules replaces the rules of the built-in run_command tool.
+// Zero limits are given their defaults.
+func SetCommandRules(r CommandRules) {
+	if r.Timeout <= 0 {
+		r.Timeout = defaultCommandTimeout
+	}
+	if r.MaxOutput <= 0 {
+		r.MaxOutput = defaultCommandOutput
+	}
+	commandMu.Lock()
+	defer commandMu.Unlock()
+	commandRules = r
+}
+
+func currentCommandRules() CommandRules {
+	commandMu.Lock()
+	defer commandMu.Unlock()
+	return commandRules
+}
+
+func commandTools() []Tool {
+	return []Tool{
+		New("run_command", "Runs a command in the workspace root and returns its exit code, stdout and stderr. Commands are checked against allow and deny rules, which are not a sandbox, and the user normally approves each call.", executeRunCommand),
+	}
+}
+
+func executeRunCommand(ctx context.Context, params RunCommandParams) (string, error) {
+	w, err := CurrentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return w.RunCommand(ctx, params, currentCommandRules())
+}
+
+// Check returns an error unless the rules let args run.
+func (r CommandRules) Check(args []string) error {
+	cmd := parseCommandWords(args)
+	for _, rule := range r.Deny {
+		if p := parseCommandWords(strings.Fields(rule)); p.denies(cmd) {
+			return fmt.Errorf("commands matching %q are denied", rule)
+		}
+	}
+	if len(r.Allow) == 0 {
+		return nil
+	}
+	for _, rule := range r.Allow {
+		if p := parseCommandWords(strings.Fields(rule)); p.allows(cmd) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not an allowed command; allowed are: %s", args[0], strings.Join(r.Allow, ", "))
+}
+
+// commandWords is a command line, or a rule, taken apart: the program,
+// the words after it in order and the set of flags. Short flags are split
+// into single letters, so -rf and -r -f are the same; long flags lose any
+// =value.
+type commandWords struct {
+	program string
+	words   []string
+	flags   map[string]bool
+}
+
+func parseCommandWords(args []string) commandWords {
+	var c commandWords
+	if len(args) == 0 {
+		return c
+	}
+	c.program = args[0]
+	c.flags = map[string]bool{}
+	flagsDone := false
+	for _, a := range args[1:] {
+		switch {
+		case flagsDone || a == "-" || !strings.HasPrefix(a, "-"):
+			c.words = append(c.words, a)
+		case a == "--":
+			flagsDone = true
+		case strings.HasPrefix(a, "--"):
+			name, _, _ := strings.Cut(a, "=")
+			c.flags[name] = true
+		default:
+			for _, r := range a[1:] {
+				c.flags["-"+string(r)] = true
+			}
+		}
+	}
+	return c
+}
+
+// sameProgram compares programs by base name, so /usr/bin/sudo matches
+// "sudo", unless the rule gives a path.
+func (p commandWords) sameProgram(cmd commandWords) bool {
+	if p.program == "" {
+		return false
+	}
+	if strings.ContainsRune(p.program, '/') {
+		return p.program == cmd.program
+	}
+	return p.program == filepath.Base(cmd.program)
+}
+
+func (p commandWords) hasFlags(cmd commandWords) bool {
+	for f := range p.flags {
+		if !cmd.flags[f] {
+			return false
+		}
+	}
+	return true
+}
+
+// allows is the strict match used for Allow: the rule's words must be the
+// command's first words.
+func (p commandWords) allows(cmd commandWords) bool {
+	if !p.sameProgram(cmd) || !p.hasFlags(cmd) || len(p.words) > len(cmd.words) {
+		return false
+	}
+	for i, w := range p.words {
+		if cmd.words[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// denies is the loose match used for Deny: the rule's words need only
+// appear in order, so "git push" also catches "git -C dir push".
+func (p commandWords) denies(cmd commandWords) bool {
+	if !p.sameProgram(cmd) || !p.hasFlags(cmd) {
+		return false
+	}
+	i := 0
+	for _, w := range cmd.words {
+		if i < len(p.words) && w == p.words[i] {
+			i++
+		}
+	}
+	return i == len(p.words)
+}
+
+// RunCommand runs a command line in the workspace root, killing it after
+// the timeout. Output is written to the context's output writer as it
+// arrives, see WithOutput. A command that runs and fails is not an error:
+// its exit code is part of the result.
+func (w *Workspace) RunCommand(ctx context.Context, p RunCommandParams, rules CommandRules) (string, error) {
+	args, err := splitCommand(p.Command)
+	if err != nil {
+		return "", err
+	}
+	if err := rules.Check(args); err != nil {
+		return "", err
+	}
+	timeout := rules.Timeout
+	if requested := time.Duration(p.Timeout) * time.Second; requested > 0 && requested < timeout {
+		timeout = requested
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = w.root
+	// Children that keep the pipes open must not hold up the result.
+	cmd.WaitDelay = time.Second
+	live := &syncWriter{w: outputFrom(ctx)}
+	stdout, stderr := newCapture(rules.MaxOutput), newCapture(rules.MaxOutput)
+	cmd.Stdout = io.MultiWriter(stdout, live)
+	cmd.Stderr = io.MultiWriter(stderr, live)
+	start := time.Now()
+	err = cmd.Run()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var b strings.Builder
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(&b, "killed: timed out after %s\n", timeout)
+	case ctx.Err() != nil:
+		return "", ctx.Err()
+	case errors.As(err, &exitErr):
+		fmt.Fprintf(&b, "exit code: %d (%s)\n", exitErr.ExitCode(), elapsed)
+	case err != nil:
+		return "", fmt.Errorf("running %s: %w", args[0], err)
+	default:
+		fmt.Fprintf(&b, "exit code: 0 (%s)\n", elapsed)
+	}
+	for _, out := range []struct {
+		name string
+		c    *capture
+	}{{"stdout", stdout}, {"stderr", stderr}} {
+		if out.c.total == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "--- %s ---\n%s", out.name, out.c)
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String(), nil
+}
+
+// splitCommand splits a command line into words the way a shell would
+// quote them, with '...', "..." and backslashes, and refuses the shell
+// syntax it does not run.
+func splitCommand(line string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case strings.ContainsRune("|&;<>()$`", r):
+			return nil, fmt.Errorf("%q needs a shell, which run_command does not use; run one program at a time", r)
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape in the command")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+	return args, nil
+}
+
+// capture keeps the start and end of a stream within a byte budget, since
+// a command's first lines say what it did and its last how it ended.
+type capture struct {
+	limit int
+	head  []byte
+	tail  []byte
+	total int
+}
+
+func newCapture(limit int) *capture {
+	return &capture{limit: limit}
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	n := len(p)
+	c.total += n
+	half := c.limit / 2
+	if room := half - len(c.head); room > 0 {
+		k := min(room, len(p))
+		c.head = append(c.head, p[:k]...)
+		p = p[k:]
+	}
+	c.tail = append(c.tail, p...)
+	if over := len(c.tail) - (c.limit - half); over > 0 {
+		c.tail = append(c.tail[:0], c.tail[over:]...)
+	}
+	return n, nil
+}
+
+func (c *capture) String() string {
+	dropped := c.total - len(c.head) - len(c.tail)
+	if dropped <= 0 {
+		return string(c.head) + string(c.tail)
+	}
+	return fmt.Sprintf("%s\n[%d bytes not shown]\n%s", c.head, dropped, c.tail)
+}
+
+// syncWriter lets stdout and stderr, copied by separate goroutines, share
+// one writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+type outputKey struct{}
+
+// WithOutput returns a context that tools which produce output over time,
+// such as run_command, write it to as it arrives, so a caller can show it
+// live. The result returned by the tool is unaffected.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+func outputFrom(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+		return w
+	}
+	return io.Discard
+}
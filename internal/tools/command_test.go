@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for line, want := range map[string]string{
+		`go test ./...`:                 "go|test|./...",
+		`grep -n "two words" 'a b.txt'`: "grep|-n|two words|a b.txt",
+		`echo a\ b "say \"hi\"" ''`:     `echo|a b|say "hi"|`,
+	} {
+		args, err := splitCommand(line)
+		if err != nil || strings.Join(args, "|") != want {
+			t.Errorf("splitCommand(%s) = %q, %v", line, args, err)
+		}
+	}
+	for _, line := range []string{"ls | wc -l", "make && rm -rf x", "cat < in", "echo $HOME", `echo "open`, "   "} {
+		if _, err := splitCommand(line); err == nil {
+			t.Errorf("splitCommand(%s) should fail", line)
+		}
+	}
+}
+
+func TestCommandRules(t *testing.T) {
+	rules := CommandRules{Allow: []string{"go test", "ls"}, Deny: []string{"go test -exec", "sudo"}}
+	for line, ok := range map[string]bool{
+		"go test ./...":          true,
+		"ls -la":                 true,
+		"go build ./...":         false,
+		"go":                     false,
+		"go test -exec x ./...":  false,
+		"go test -v -exec x":     false,
+		"/usr/bin/ls":            true,
+		"sudo ls":                false,
+		"lsof":                   false,
+		"go testing":             false,
+		"go run test":            false,
+		"go  test  -run  TestX ": true,
+	} {
+		args, err := splitCommand(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rules.Check(args); (err == nil) != ok {
+			t.Errorf("Check(%s) = %v", line, err)
+		}
+	}
+	if err := (CommandRules{Deny: []string{"sudo"}}).Check([]string{"rm", "x"}); err != nil {
+		t.Errorf("an empty allowlist should allow what is not denied: %v", err)
+	}
+}
+
+func TestDefaultDeniedCommands(t *testing.T) {
+	rules := CommandRules{Deny: DefaultDeniedCommands}
+	for line, ok := range map[string]bool{
+		`bash -c "rm -rf ~"`:           false,
+		"sh -c ls":                     false,
+		"/bin/sh -c ls":                false,
+		"env rm -rf x":                 false,
+		"xargs rm":                     false,
+		"rm -rf build":                 false,
+		"rm -fr build":                 false,
+		"rm -r -f build":               false,
+		"rm -Rf build":                 false,
+		"rm --recursive --force build": false,
+		"find . -name x -delete":       false,
+		"git -C repo push origin":      false,
+		"sudo ls":                      false,
+		"rm -f build/out.txt":          true,
+		"go test ./...":                true,
+		"git status":                   true,
+		"find . -name *.go":            true,
+	} {
+		args, err := splitCommand(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rules.Check(args); (err == nil) != ok {
+			t.Errorf("Check(%s) = %v", line, err)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	w := newFixture(t)
+	rules := CommandRules{Timeout: 10 * time.Second, MaxOutput: 1024}
+	var live bytes.Buffer
+	ctx := WithOutput(context.Background(), &live)
+
+	out, err := w.RunCommand(ctx, RunCommandParams{Command: `sh -c 'cat go.mod; echo oops >&2; exit 3'`}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"exit code: 3", "--- stdout ---\nmodule demo\n", "--- stderr ---\noops\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("result lacks %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(live.String(), "module demo") || !strings.Contains(live.String(), "oops") {
+		t.Errorf("output not streamed: %q", live.String())
+	}
+
+	out, err = w.RunCommand(ctx, RunCommandParams{Command: `sh -c 'yes | head -c 100000'`}, rules)
+	if err != nil || len(out) > 1200 || !strings.Contains(out, "bytes not shown") {
+		t.Errorf("large output not capped: %d bytes, %v", len(out), err)
+	}
+
+	start := time.Now()
+	out, err = w.RunCommand(ctx, RunCommandParams{Command: "sleep 10", Timeout: 1}, rules)
+	if err != nil || !strings.Contains(out, "timed out after 1s") || time.Since(start) > 5*time.Second {
+		t.Errorf("timeout = %q, %v after %s", out, err, time.Since(start))
+	}
+
+	if _, err := w.RunCommand(ctx, RunCommandParams{Command: "sh -c true"}, CommandRules{Allow: []string{"go"}, Timeout: time.Second}); err == nil {
+		t.Error("a command outside the allowlist ran")
+	}
+	if _, err := w.RunCommand(ctx, RunCommandParams{Command: "no-such-program-clai"}, rules); err == nil {
+		t.Error("expected a missing program to fail")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	c := newCapture(10)
+	for _, s := range []string{"abc", "defgh", "ijklmnop", "qrstuvwxyz"} {
+		c.Write([]byte(s))
+	}
+	if got := c.String(); got != "abcde\n[16 bytes not shown]\nvwxyz" {
+		t.Errorf("capture = %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around a change.
+	diffContext = 3
+	// maxDiffCells bounds the table used to line up the changed middle of
+	// two files; beyond it the middle is shown as replaced outright.
+	maxDiffCells = 4 << 20
+)
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+'
+// added. Lines keep their newline, except a last line without one.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// Diff returns a unified diff turning old into new, with name in the
+// headers. A created file is diffed against /dev/null. It returns "" when
+// nothing changed.
+func Diff(name string, old, new []byte, created bool) string {
+	ops := lineOps(splitLines(string(old)), splitLines(string(new)))
+	hunks := unifiedHunks(ops)
+	if hunks == "" {
+		return ""
+	}
+	from := "a/" + name
+	if created {
+		from = "/dev/null"
+	}
+	return fmt.Sprintf("--- %s\n+++ b/%s\n%s", from, name, hunks)
+}
+
+// splitLines splits text after each newline.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOps works out the edit script from a to b: the common head and tail
+// are kept and the middle is aligned on its longest common subsequence.
+func lineOps(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b)-pre-suf)
+	for _, l := range a[:pre] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	ops = append(ops, middleOps(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+func middleOps(a, b []string) []diffOp {
+	var ops []diffOp
+	n, m := len(a), len(b)
+	if n*m > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	lcs := make([]int, (n+1)*(m+1))
+	at := func(i, j int) int { return i*(m+1) + j }
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[at(i, j)] = lcs[at(i+1, j+1)] + 1
+			} else {
+				lcs[at(i, j)] = max(lcs[at(i+1, j)], lcs[at(i, j+1)])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i, j = i+1, j+1
+		case lcs[at(i+1, j)] >= lcs[at(i, j+1)]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedHunks formats the changes in ops as unified diff hunks, merging
+// changes whose context would overlap.
+func unifiedHunks(ops []diffOp) string {
+	// aPos[k] and bPos[k] count the old and new lines before ops[k].
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	var changes []int
+	for k, op := range ops {
+		aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+		if op.kind != '+' {
+			aPos[k+1]++
+		}
+		if op.kind != '-' {
+			bPos[k+1]++
+		}
+		if op.kind != ' ' {
+			changes = append(changes, k)
+		}
+	}
+	var b strings.Builder
+	for c := 0; c < len(changes); {
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*diffContext {
+			last++
+		}
+		start := max(changes[c]-diffContext, 0)
+		end := min(changes[last]+diffContext+1, len(ops))
+		aLen, bLen := aPos[end]-aPos[start], bPos[end]-bPos[start]
+		aStart, bStart := aPos[start], bPos[start]
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		c = last + 1
+	}
+	return b.String()
+}
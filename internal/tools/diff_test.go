@@ -0,0 +1,34 @@
+package tools
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk"
+	want := `--- a/x.txt
++++ b/x.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+\ No newline at end of file
+`
+	if got := Diff("x.txt", []byte(old), []byte(new), false); got != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", got, want)
+	}
+	if got := Diff("x.txt", []byte(old), []byte(old), false); got != "" {
+		t.Errorf("Diff of equal content = %q", got)
+	}
+	want = "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,1 @@\n+hi\n"
+	if got := Diff("new.txt", nil, []byte("hi\n"), true); got != want {
+		t.Errorf("Diff of a new file = %q", got)
+	}
+}
@@ -0,0 +1,321 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Previewer is implemented by tools that change files. Preview returns, as
+// a unified diff, what a call would change without changing anything. Such
+// calls only run once the user has seen the preview and approved it.
+type Previewer interface {
+	Preview(ctx context.Context, params json.RawMessage) (string, error)
+}
+
+type WriteFileParams struct {
+	Path    string `json:"path" description:"File to create or overwrite, relative to the workspace root" required:"true"`
+	Content string `json:"content" description:"The complete new contents of the file" required:"true"`
+}
+
+type ApplyPatchParams struct {
+	Patch string `json:"patch" description:"A unified diff with --- and +++ headers, or search/replace blocks: <<<<<<< SEARCH, the exact lines to find, =======, their replacement, >>>>>>> REPLACE" required:"true"`
+	Path  string `json:"path,omitempty" description:"File the search/replace blocks apply to, if not named on the line before them"`
+}
+
+func editTools() []Tool {
+	return []Tool{
+		newEditTool("write_file", "Creates a file in the workspace, or replaces the whole of an existing one. The user reviews the change before it is made.", planWrite),
+		newEditTool("apply_patch", "Edits files in the workspace with a unified diff or search/replace blocks. The user reviews the change before it is made.", planPatch),
+	}
+}
+
+// FileChange is the new content planned for one file.
+type FileChange struct {
+	// Path is relative to the workspace root.
+	Path string
+	Old  []byte
+	New  []byte
+	// Created is set when the file does not exist yet.
+	Created bool
+	real    string
+}
+
+// Edit is one applied tool call, kept in the workspace journal so it can be
+// undone.
+type Edit struct {
+	Tool    string
+	Time    time.Time
+	Changes []FileChange
+}
+
+// editTool is a tool whose calls are planned as FileChanges, previewed as
+// a diff and then applied to the current workspace.
+type editTool[P any] struct {
+	name        string
+	description string
+	schema      *Schema
+	plan        func(w *Workspace, params P) ([]FileChange, error)
+}
+
+func newEditTool[P any](name, description string, plan func(*Workspace, P) ([]FileChange, error)) Tool {
+	var zero P
+	return &editTool[P]{name: name, description: description, schema: SchemaFor(zero), plan: plan}
+}
+
+func (t *editTool[P]) Name() string        { return t.name }
+func (t *editTool[P]) Description() string { return t.description }
+func (t *editTool[P]) Schema() *Schema     { return t.schema }
+
+func (t *editTool[P]) changes(params json.RawMessage) (*Workspace, []FileChange, error) {
+	var p P
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, nil, fmt.Errorf("error unmarshalling %s params: %w", t.name, err)
+		}
+	}
+	w, err := CurrentWorkspace()
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := t.plan(w, p)
+	return w, changes, err
+}
+
+func (t *editTool[P]) Preview(ctx context.Context, params json.RawMessage) (string, error) {
+	_, changes, err := t.changes(params)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(Diff(c.Path, c.Old, c.New, c.Created))
+	}
+	return b.String(), nil
+}
+
+func (t *editTool[P]) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	w, changes, err := t.changes(params)
+	if err != nil {
+		return "", err
+	}
+	return w.apply(t.name, changes)
+}
+
+func planWrite(w *Workspace, p WriteFileParams) ([]FileChange, error) {
+	c, err := w.plan(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	c.New = []byte(p.Content)
+	if !c.Created && bytes.Equal(c.Old, c.New) {
+		return nil, fmt.Errorf("%s already has that content", c.Path)
+	}
+	return []FileChange{c}, nil
+}
+
+func planPatch(w *Workspace, p ApplyPatchParams) ([]FileChange, error) {
+	edits, err := parsePatch(p.Patch, p.Path)
+	if err != nil {
+		return nil, err
+	}
+	// A patch may touch a file more than once; later edits build on
+	// earlier ones.
+	var changes []FileChange
+	index := map[string]int{}
+	for _, e := range edits {
+		i, seen := index[e.path]
+		if !seen {
+			c, err := w.plan(e.path)
+			if err != nil {
+				return nil, err
+			}
+			if e.create && !c.Created {
+				return nil, fmt.Errorf("%s already exists", c.Path)
+			}
+			c.New = c.Old
+			i = len(changes)
+			index[e.path] = i
+			changes = append(changes, c)
+		}
+		content, err := applyEdit(string(changes[i].New), e)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", changes[i].Path, err)
+		}
+		changes[i].New = []byte(content)
+	}
+	for _, c := range changes {
+		if !c.Created && bytes.Equal(c.Old, c.New) {
+			return nil, fmt.Errorf("the patch does not change %s", c.Path)
+		}
+	}
+	return changes, nil
+}
+
+// plan starts a FileChange for path with its current content.
+func (w *Workspace) plan(path string) (FileChange, error) {
+	real, err := w.Resolve(path)
+	if err != nil {
+		return FileChange{}, err
+	}
+	c := FileChange{Path: w.Rel(real), real: real}
+	data, err := readText(real)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		c.Created = true
+	case err != nil:
+		return FileChange{}, fmt.Errorf("%s: %w", path, err)
+	default:
+		c.Old = data
+	}
+	return c, nil
+}
+
+// apply writes the planned changes, after checking no file has changed
+// since they were planned, and journals them.
+func (w *Workspace) apply(tool string, changes []FileChange) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var done []FileChange
+	var summary []string
+	for _, c := range changes {
+		if err := checkContent(c.real, c.Old, c.Created); err != nil {
+			return "", w.failed(tool, done, fmt.Errorf("%s: %w", c.Path, err))
+		}
+		if err := writeFileAtomic(c.real, c.New); err != nil {
+			return "", w.failed(tool, done, fmt.Errorf("writing %s: %w", c.Path, err))
+		}
+		done = append(done, c)
+		verb := "updated"
+		if c.Created {
+			verb = "created"
+		}
+		added, removed := countChanges(c)
+		summary = append(summary, fmt.Sprintf("%s %s (+%d -%d)", verb, c.Path, added, removed))
+	}
+	w.journal = append(w.journal, Edit{Tool: tool, Time: time.Now(), Changes: done})
+	return strings.Join(summary, "\n"), nil
+}
+
+// failed journals the changes already written before err stopped a call,
+// so they can still be undone.
+func (w *Workspace) failed(tool string, done []FileChange, err error) error {
+	if len(done) > 0 {
+		w.journal = append(w.journal, Edit{Tool: tool, Time: time.Now(), Changes: done})
+		return fmt.Errorf("%w (earlier files in the call were written)", err)
+	}
+	return err
+}
+
+func countChanges(c FileChange) (added, removed int) {
+	for _, op := range lineOps(splitLines(string(c.Old)), splitLines(string(c.New))) {
+		switch op.kind {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// checkContent verifies that the file at real holds want, or does not
+// exist if absent is set.
+func checkContent(real string, want []byte, absent bool) error {
+	data, err := os.ReadFile(real)
+	if absent {
+		if err == nil {
+			return errors.New("was created since the change was planned")
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, want) {
+		return errors.New("has changed since the change was planned")
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file through a temporary file, so it is
+// never left half written, keeping its permissions.
+func writeFileAtomic(name string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".clai-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Edits returns the journal of applied edits, oldest first.
+func (w *Workspace) Edits() []Edit {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Edit(nil), w.journal...)
+}
+
+// Undo reverts the last n edits, newest first, and returns those it
+// reverted. It stops at a file that has changed since the edit, leaving
+// that edit and older ones in the journal.
+func (w *Workspace) Undo(n int) ([]Edit, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var undone []Edit
+	for ; n > 0 && len(w.journal) > 0; n-- {
+		e := w.journal[len(w.journal)-1]
+		for _, c := range e.Changes {
+			if err := checkContent(c.real, c.New, false); err != nil {
+				return undone, fmt.Errorf("not undoing %s: %s %w", e.Tool, c.Path, err)
+			}
+		}
+		for i := len(e.Changes) - 1; i >= 0; i-- {
+			c := e.Changes[i]
+			var err error
+			if c.Created {
+				err = os.Remove(c.real)
+			} else {
+				err = writeFileAtomic(c.real, c.Old)
+			}
+			if err != nil {
+				// The files after this one are already reverted.
+				e.Changes = e.Changes[:i+1]
+				w.journal[len(w.journal)-1] = e
+				return undone, fmt.Errorf("undoing %s: %w", c.Path, err)
+			}
+		}
+		w.journal = w.journal[:len(w.journal)-1]
+		undone = append(undone, e)
+	}
+	return undone, nil
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useWorkspace makes w the workspace of the built-in tools for the test.
+func useWorkspace(t *testing.T, w *Workspace) {
+	t.Helper()
+	workspaceMu.Lock()
+	defaultWorkspace = w
+	workspaceMu.Unlock()
+	t.Cleanup(func() {
+		workspaceMu.Lock()
+		defaultWorkspace = nil
+		workspaceMu.Unlock()
+	})
+}
+
+func registeredEditTool(t *testing.T, name string) (Tool, Previewer) {
+	t.Helper()
+	tool, ok := DefaultRegistry.Get(name)
+	if !ok {
+		t.Fatalf("%s is not registered", name)
+	}
+	p, ok := DefaultRegistry.Previewer(name)
+	if !ok {
+		t.Fatalf("%s has no preview", name)
+	}
+	return tool, p
+}
+
+func readFixture(t *testing.T, w *Workspace, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(w.Root(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteFilePreviewsThenWrites(t *testing.T) {
+	w := newFixture(t)
+	useWorkspace(t, w)
+	ctx := context.Background()
+	tool, p := registeredEditTool(t, "write_file")
+
+	params := json.RawMessage(`{"path":"notes/todo.txt","content":"write tests\n"}`)
+	diff, err := p.Preview(ctx, params)
+	if err != nil || diff != "--- /dev/null\n+++ b/notes/todo.txt\n@@ -0,0 +1,1 @@\n+write tests\n" {
+		t.Fatalf("Preview = %q, %v", diff, err)
+	}
+	if _, err := os.Stat(filepath.Join(w.Root(), "notes")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("preview touched the disk: %v", err)
+	}
+	out, err := tool.Execute(ctx, params)
+	if err != nil || out != "created notes/todo.txt (+1 -0)" {
+		t.Errorf("Execute = %q, %v", out, err)
+	}
+	if got := readFixture(t, w, "notes/todo.txt"); got != "write tests\n" {
+		t.Errorf("file holds %q", got)
+	}
+
+	for _, path := range []string{"../secret.txt", "escape", "outside/x.txt"} {
+		params := json.RawMessage(`{"path":"` + path + `","content":"x"}`)
+		if _, err := p.Preview(ctx, params); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("Preview(%s) = %v, want ErrOutsideWorkspace", path, err)
+		}
+		if _, err := tool.Execute(ctx, params); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("Execute(%s) = %v, want ErrOutsideWorkspace", path, err)
+		}
+	}
+	if got := readFixture(t, w, "../secret.txt"); got != "password" {
+		t.Errorf("secret overwritten with %q", got)
+	}
+}
+
+func TestApplyPatchAndUndo(t *testing.T) {
+	w := newFixture(t)
+	useWorkspace(t, w)
+	ctx := context.Background()
+	tool, _ := registeredEditTool(t, "apply_patch")
+	write, _ := registeredEditTool(t, "write_file")
+
+	patch := "internal/a/a.go\n<<<<<<< SEARCH\n\"hello\"\n=======\n\"hi\"\n>>>>>>> REPLACE\n"
+	params, _ := json.Marshal(ApplyPatchParams{Patch: patch})
+	if _, err := tool.Execute(ctx, params); err == nil {
+		t.Fatal("a SEARCH section must match whole lines")
+	}
+	patch = "internal/a/a.go\n<<<<<<< SEARCH\nfunc Hello() string { return \"hello\" }\n=======\nfunc Hello() string { return \"hi\" }\n>>>>>>> REPLACE\n"
+	params, _ = json.Marshal(ApplyPatchParams{Patch: patch})
+	if out, err := tool.Execute(ctx, params); err != nil || out != "updated internal/a/a.go (+1 -1)" {
+		t.Fatalf("Execute = %q, %v", out, err)
+	}
+	if _, err := write.Execute(ctx, json.RawMessage(`{"path":"new.txt","content":"new\n"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if edits := w.Edits(); len(edits) != 2 || edits[0].Tool != "apply_patch" || edits[1].Tool != "write_file" {
+		t.Fatalf("journal = %+v", edits)
+	}
+
+	undone, err := w.Undo(5)
+	if err != nil || len(undone) != 2 {
+		t.Fatalf("Undo = %d edits, %v", len(undone), err)
+	}
+	if _, err := os.Stat(filepath.Join(w.Root(), "new.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("created file survived undo: %v", err)
+	}
+	if got := readFixture(t, w, "internal/a/a.go"); !strings.Contains(got, `return "hello"`) {
+		t.Errorf("patch not undone:\n%s", got)
+	}
+	if undone, err := w.Undo(1); err != nil || len(undone) != 0 {
+		t.Errorf("Undo of an empty journal = %v, %v", undone, err)
+	}
+}
+
+func TestUndoRefusesChangedFiles(t *testing.T) {
+	w := newFixture(t)
+	useWorkspace(t, w)
+	write, _ := registeredEditTool(t, "write_file")
+	if _, err := write.Execute(context.Background(), json.RawMessage(`{"path":"go.mod","content":"module other\n"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(w.Root(), "go.mod"), []byte("module mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Undo(1); err == nil || !strings.Contains(err.Error(), "has changed") {
+		t.Errorf("Undo over a later change = %v", err)
+	}
+	if got := readFixture(t, w, "go.mod"); got != "module mine\n" {
+		t.Errorf("later change lost: %q", got)
+	}
+	if len(w.Edits()) != 1 {
+		t.Error("a refused undo should stay in the journal")
+	}
+}
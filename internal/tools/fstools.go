@@ -0,0 +1,416 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxOutput caps what a filesystem tool returns, in bytes, to keep the
+	// result within reach of a small context window.
+	maxOutput = 16 * 1024
+	// maxFileSize is the largest file read_file and grep will open.
+	maxFileSize = 4 << 20
+	// maxResults caps the entries listed by list_dir and glob and the
+	// matching lines shown by grep.
+	maxResults = 200
+	// maxLineLength shortens long lines in grep output.
+	maxLineLength = 240
+	// sniffSize is how much of a file is checked for binary content.
+	sniffSize = 8000
+)
+
+type ReadFileParams struct {
+	Path   string `json:"path" description:"File to read, relative to the workspace root" required:"true"`
+	Offset int    `json:"offset,omitempty" description:"Line to start at, counting from 1"`
+	Limit  int    `json:"limit,omitempty" description:"Maximum number of lines to read"`
+}
+
+type ListDirParams struct {
+	Path string `json:"path,omitempty" description:"Directory to list, relative to the workspace root; defaults to the root"`
+}
+
+type GrepParams struct {
+	Pattern    string `json:"pattern" description:"Regular expression (RE2 syntax) to search for" required:"true"`
+	Path       string `json:"path,omitempty" description:"File or directory to search, relative to the workspace root; defaults to the root"`
+	Include    string `json:"include,omitempty" description:"Only search files whose name matches this glob, e.g. *.go"`
+	IgnoreCase bool   `json:"ignore_case,omitempty" description:"Match without regard to case"`
+}
+
+type GlobParams struct {
+	Pattern string `json:"pattern" description:"Glob relative to the workspace root; ** matches any number of directories, e.g. internal/**/*_test.go" required:"true"`
+}
+
+func fsTools() []Tool {
+	return []Tool{
+		New("read_file", "Reads a text file in the workspace, optionally a range of lines. Lines are numbered.", executeReadFile),
+		New("list_dir", "Lists the files and directories in a workspace directory.", executeListDir),
+		New("grep", "Searches the files in the workspace for lines matching a regular expression.", executeGrep),
+		New("glob", "Finds the workspace files whose path matches a glob pattern.", executeGlob),
+	}
+}
+
+func executeReadFile(ctx context.Context, params ReadFileParams) (string, error) {
+	w, err := CurrentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return w.ReadFile(ctx, params)
+}
+
+func executeListDir(ctx context.Context, params ListDirParams) (string, error) {
+	w, err := CurrentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return w.ListDir(ctx, params)
+}
+
+func executeGrep(ctx context.Context, params GrepParams) (string, error) {
+	w, err := CurrentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return w.Grep(ctx, params)
+}
+
+func executeGlob(ctx context.Context, params GlobParams) (string, error) {
+	w, err := CurrentWorkspace()
+	if err != nil {
+		return "", err
+	}
+	return w.Glob(ctx, params)
+}
+
+// ReadFile returns the numbered lines of a text file.
+func (w *Workspace) ReadFile(ctx context.Context, params ReadFileParams) (string, error) {
+	real, err := w.Resolve(params.Path)
+	if err != nil {
+		return "", err
+	}
+	data, err := readText(real)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", params.Path, err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	start := max(params.Offset, 1)
+	if start > len(lines) && len(lines) > 0 {
+		return "", fmt.Errorf("%s has %d lines; offset %d is past the end", params.Path, len(lines), start)
+	}
+	end := len(lines)
+	if params.Limit > 0 {
+		end = min(end, start-1+params.Limit)
+	}
+	var out strings.Builder
+	for i := start; i <= end; i++ {
+		line := fmt.Sprintf("%6d\t%s", i, lines[i-1])
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if out.Len()+len(line) > maxOutput {
+			fmt.Fprintf(&out, "[truncated after line %d of %d; read on with offset %d]\n", i-1, len(lines), i)
+			break
+		}
+		out.WriteString(line)
+	}
+	if out.Len() == 0 {
+		return params.Path + " is empty", nil
+	}
+	return out.String(), nil
+}
+
+// ListDir lists a directory, marking subdirectories with a trailing slash
+// and giving the size of files.
+func (w *Workspace) ListDir(ctx context.Context, params ListDirParams) (string, error) {
+	real, err := w.Resolve(params.Path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(real)
+	if err != nil {
+		return "", fmt.Errorf("listing %s: %w", params.Path, err)
+	}
+	if len(entries) == 0 {
+		return "The directory is empty.", nil
+	}
+	var out limitedWriter
+	for i, e := range entries {
+		if i == maxResults {
+			out.printf("[%d more entries not shown]\n", len(entries)-i)
+			break
+		}
+		switch info, err := e.Info(); {
+		case e.IsDir():
+			out.printf("%s/\n", e.Name())
+		case err == nil && e.Type()&fs.ModeSymlink != 0:
+			out.printf("%s@\n", e.Name())
+		case err == nil:
+			out.printf("%s\t%d bytes\n", e.Name(), info.Size())
+		default:
+			out.printf("%s\n", e.Name())
+		}
+	}
+	return out.String(), nil
+}
+
+// Grep searches text files under a path for a regular expression.
+func (w *Workspace) Grep(ctx context.Context, params GrepParams) (string, error) {
+	pattern := params.Pattern
+	if params.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	if params.Include != "" {
+		if _, err := path.Match(params.Include, ""); err != nil {
+			return "", fmt.Errorf("invalid include glob: %w", err)
+		}
+	}
+	real, err := w.Resolve(params.Path)
+	if err != nil {
+		return "", err
+	}
+	var out limitedWriter
+	matches := 0
+	err = w.walk(ctx, real, func(file string) error {
+		if params.Include != "" {
+			if ok, _ := path.Match(params.Include, filepath.Base(file)); !ok {
+				return nil
+			}
+		}
+		data, err := readText(file)
+		if err != nil {
+			// Binary, oversized and unreadable files are skipped.
+			return nil
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+		for n := 1; scanner.Scan(); n++ {
+			line := scanner.Text()
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches == maxResults || out.full {
+				return errStopWalk
+			}
+			matches++
+			out.printf("%s:%d: %s\n", w.Rel(file), n, shorten(line, maxLineLength))
+		}
+		return nil
+	})
+	stopped := errors.Is(err, errStopWalk)
+	if err != nil && !stopped {
+		return "", err
+	}
+	if matches == 0 {
+		return "No matches.", nil
+	}
+	if stopped {
+		return out.String() + "[more matches not shown; narrow the pattern, path or include]\n", nil
+	}
+	return out.String(), nil
+}
+
+// Glob lists the files whose path relative to the root matches a pattern.
+func (w *Workspace) Glob(ctx context.Context, params GlobParams) (string, error) {
+	pattern := strings.TrimPrefix(path.Clean(filepath.ToSlash(params.Pattern)), "./")
+	if strings.HasPrefix(pattern, "/") || pattern == ".." || strings.HasPrefix(pattern, "../") {
+		return "", fmt.Errorf("%s: %w", params.Pattern, ErrOutsideWorkspace)
+	}
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return "", fmt.Errorf("invalid glob: %w", err)
+	}
+	var found []string
+	err := w.walk(ctx, w.root, func(file string) error {
+		rel := w.Rel(file)
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			found = append(found, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "No files match.", nil
+	}
+	sort.Strings(found)
+	var out limitedWriter
+	for i, f := range found {
+		if i == maxResults {
+			out.printf("[%d more files not shown]\n", len(found)-i)
+			break
+		}
+		out.printf("%s\n", f)
+	}
+	return out.String(), nil
+}
+
+// matchGlob matches path segments against pattern segments, where a "**"
+// segment matches any number of path segments.
+func matchGlob(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchGlob(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+var errStopWalk = errors.New("stop walking")
+
+// skipDirs are not searched; they are large and rarely what is asked about.
+var skipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// walk calls fn with every regular file under root, which may itself be a
+// file. Directories in skipDirs are left out, and symbolic links are only
+// followed to files inside the workspace.
+func (w *Workspace) walk(ctx context.Context, root string, fn func(file string) error) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped rather than ending the search.
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if p != root && skipDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			real, err := filepath.EvalSymlinks(p)
+			if err != nil || !w.contains(real) {
+				return nil
+			}
+			if info, err := os.Stat(real); err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			return fn(p)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+var errBinary = errors.New("binary file")
+
+// readText reads a file, failing for directories, files over maxFileSize
+// and files that look binary.
+func readText(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("is a directory; use list_dir")
+	}
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("file is %d bytes, over the %d byte limit", info.Size(), maxFileSize)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if isBinary(data) {
+		return nil, fmt.Errorf("%w of %d bytes; its contents cannot be shown", errBinary, len(data))
+	}
+	return data, nil
+}
+
+// isBinary reports whether data looks like something other than text: it
+// has a NUL byte or is not UTF-8 near the start.
+func isBinary(data []byte) bool {
+	sniff := data[:min(len(data), sniffSize)]
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return true
+	}
+	truncated := len(sniff) < len(data)
+	for rest := sniff; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		if r == utf8.RuneError && size == 1 {
+			// A character cut in two by the end of the sample is fine, but
+			// not one cut off by the end of the file.
+			return !truncated || utf8.FullRune(rest)
+		}
+		rest = rest[size:]
+	}
+	return false
+}
+
+func shorten(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+// limitedWriter collects tool output up to maxOutput bytes, dropping the
+// rest and noting that it did.
+type limitedWriter struct {
+	b    strings.Builder
+	full bool
+}
+
+func (l *limitedWriter) printf(format string, args ...any) {
+	if l.full {
+		return
+	}
+	s := fmt.Sprintf(format, args...)
+	if l.b.Len()+len(s) > maxOutput {
+		l.full = true
+		return
+	}
+	l.b.WriteString(s)
+}
+
+func (l *limitedWriter) String() string {
+	if l.full {
+		return l.b.String() + "[output truncated]\n"
+	}
+	return l.b.String()
+}
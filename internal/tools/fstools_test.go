@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFixture builds a small tree in a temp dir, with a secret next to the
+// workspace and links pointing at it from inside:
+//
+//	secret.txt
+//	repo/
+//	  README.md
+//	  go.mod
+//	  image.png        (binary)
+//	  internal/a/a.go
+//	  internal/a/a_test.go
+//	  .git/config
+//	  escape -> ../secret.txt
+//	  outside -> ..
+//	  readme -> README.md
+func newFixture(t *testing.T) *Workspace {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"secret.txt":                "password",
+		"repo/README.md":            "# Demo\nRun go test.\n",
+		"repo/go.mod":               "module demo\n",
+		"repo/image.png":            "\x89PNG\r\n\x1a\n\x00\x00",
+		"repo/internal/a/a.go":      "package a\n\nfunc Hello() string { return \"hello\" }\n",
+		"repo/internal/a/a_test.go": "package a\n\n// TestHello checks Hello.\n",
+		"repo/.git/config":          "hello from git\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{"escape": "../secret.txt", "outside": "..", "readme": "README.md"} {
+		if err := os.Symlink(target, filepath.Join(dir, "repo", link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	w, err := NewWorkspace(filepath.Join(dir, "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestWorkspaceRejectsEscapes(t *testing.T) {
+	w := newFixture(t)
+	ctx := context.Background()
+	for _, path := range []string{"../secret.txt", "internal/../../secret.txt", "escape", "outside/secret.txt", "/etc/passwd"} {
+		if _, err := w.ReadFile(ctx, ReadFileParams{Path: path}); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("ReadFile(%s) = %v, want ErrOutsideWorkspace", path, err)
+		}
+	}
+	if _, err := w.ListDir(ctx, ListDirParams{Path: "outside"}); !errors.Is(err, ErrOutsideWorkspace) {
+		t.Errorf("ListDir through a link out = %v", err)
+	}
+	if _, err := w.Glob(ctx, GlobParams{Pattern: "../*"}); !errors.Is(err, ErrOutsideWorkspace) {
+		t.Errorf("Glob above the root = %v", err)
+	}
+	if out, err := w.ReadFile(ctx, ReadFileParams{Path: filepath.Join(w.Root(), "readme")}); err != nil || !strings.Contains(out, "# Demo") {
+		t.Errorf("absolute path through a link inside = %q, %v", out, err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	w := newFixture(t)
+	ctx := context.Background()
+	out, err := w.ReadFile(ctx, ReadFileParams{Path: "internal/a/a.go", Offset: 3, Limit: 1})
+	if err != nil || out != "     3\tfunc Hello() string { return \"hello\" }\n" {
+		t.Errorf("ReadFile = %q, %v", out, err)
+	}
+	if _, err := w.ReadFile(ctx, ReadFileParams{Path: "image.png"}); err == nil || !strings.Contains(err.Error(), "binary file") {
+		t.Errorf("binary file: %v", err)
+	}
+	if _, err := w.ReadFile(ctx, ReadFileParams{Path: "README.md", Offset: 10}); err == nil {
+		t.Error("expected an offset past the end to fail")
+	}
+
+	big := strings.Repeat(strings.Repeat("x", 99)+"\n", 1000)
+	if err := os.WriteFile(filepath.Join(w.Root(), "big.txt"), []byte(big), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = w.ReadFile(ctx, ReadFileParams{Path: "big.txt"})
+	if err != nil || len(out) > maxOutput+100 || !strings.Contains(out, "read on with offset") {
+		t.Errorf("large file not capped: %d bytes, %v", len(out), err)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	// "é" is two bytes; the sample ends after its first.
+	cut := strings.Repeat("x", sniffSize-1) + "é"
+	for _, tc := range []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"text", "héllo\n", false},
+		{"NUL", "a\x00b", true},
+		{"invalid UTF-8", "a\xffb", true},
+		{"file ends mid-character", "abc\xc3", true},
+		{"sample ends mid-character", cut, false},
+		{"invalid byte before the sample end", strings.Repeat("x", sniffSize-1) + "\xff", true},
+	} {
+		if got := isBinary([]byte(tc.data)); got != tc.want {
+			t.Errorf("%s: isBinary = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestListDir(t *testing.T) {
+	w := newFixture(t)
+	out, err := w.ListDir(context.Background(), ListDirParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{".git/\n", "internal/\n", "go.mod\t12 bytes\n", "readme@\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("listing lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	w := newFixture(t)
+	ctx := context.Background()
+	out, err := w.Grep(ctx, GrepParams{Pattern: "HELLO", IgnoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// .git is skipped, and the link to the secret is not followed.
+	want := "internal/a/a.go:3: func Hello() string { return \"hello\" }\ninternal/a/a_test.go:3: // TestHello checks Hello.\n"
+	if out != want {
+		t.Errorf("Grep =\n%s\nwant\n%s", out, want)
+	}
+	out, err = w.Grep(ctx, GrepParams{Pattern: "Demo|password"})
+	if err != nil || out != "README.md:1: # Demo\nreadme:1: # Demo\n" {
+		t.Errorf("Grep through links = %q, %v", out, err)
+	}
+	out, err = w.Grep(ctx, GrepParams{Pattern: "Demo", Include: "*.md"})
+	if err != nil || out != "README.md:1: # Demo\n" {
+		t.Errorf("Grep with include = %q, %v", out, err)
+	}
+	if _, err := w.Grep(ctx, GrepParams{Pattern: "("}); err == nil {
+		t.Error("expected an invalid pattern to fail")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	w := newFixture(t)
+	for pattern, want := range map[string]string{
+		"**/*_test.go": "internal/a/a_test.go\n",
+		"*.md":         "README.md\n",
+		"internal/**":  "internal/a/a.go\ninternal/a/a_test.go\n",
+		"*.txt":        "No files match.",
+	} {
+		out, err := w.Glob(context.Background(), GlobParams{Pattern: pattern})
+		if err != nil || out != want {
+			t.Errorf("Glob(%s) = %q, %v; want %q", pattern, out, err, want)
+		}
+	}
+}
+
+func TestFilesystemToolsAreRegistered(t *testing.T) {
+	w := newFixture(t)
+	t.Cleanup(func() {
+		workspaceMu.Lock()
+		defaultWorkspace = nil
+		workspaceMu.Unlock()
+	})
+	if err := SetWorkspace(w.Root()); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ExecuteTool(context.Background(), "glob", json.RawMessage(`{"pattern":"go.mod"}`))
+	if err != nil || out != "go.mod\n" {
+		t.Errorf("glob tool = %q, %v", out, err)
+	}
+}
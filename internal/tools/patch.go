@@ -0,0 +1,294 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A patch is either a unified diff, as produced by `diff -u` or `git
+// diff`, or a series of search/replace blocks:
+//
+//	path/to/file.go
+//	<<<<<<< SEARCH
+//	lines to find
+//	=======
+//	lines to put in their place
+//	>>>>>>> REPLACE
+
+const (
+	searchMarker  = "<<<<<<< SEARCH"
+	dividerMarker = "======="
+	replaceMarker = ">>>>>>> REPLACE"
+)
+
+// fileEdit is what a patch asks of one file: either hunks to apply or
+// blocks to replace.
+type fileEdit struct {
+	path   string
+	create bool
+	hunks  []hunk
+	blocks []replaceBlock
+}
+
+// hunk is one @@ section of a unified diff; oldStart is where it claims to
+// apply, counting from 1.
+type hunk struct {
+	oldStart int
+	ops      []diffOp
+}
+
+type replaceBlock struct {
+	search, replace []string
+}
+
+// parsePatch reads either kind of patch. defaultPath names the file for
+// search/replace blocks that are not preceded by one.
+func parsePatch(patch, defaultPath string) ([]fileEdit, error) {
+	if strings.Contains(patch, searchMarker) {
+		return parseReplaceBlocks(patch, defaultPath)
+	}
+	return parseUnifiedDiff(patch)
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+func parseUnifiedDiff(patch string) ([]fileEdit, error) {
+	lines := splitLines(patch)
+	var edits []fileEdit
+	var cur *fileEdit
+	var h *hunk
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath, newPath := diffPath(line[4:]), diffPath(lines[i+1][4:])
+			if newPath == "/dev/null" {
+				return nil, fmt.Errorf("deleting %s is not supported", oldPath)
+			}
+			edits = append(edits, fileEdit{path: newPath, create: oldPath == "/dev/null"})
+			cur, h = &edits[len(edits)-1], nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("hunk before any --- and +++ file header")
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("malformed hunk header %q", strings.TrimSpace(line))
+			}
+			start, _ := strconv.Atoi(m[1])
+			cur.hunks = append(cur.hunks, hunk{oldStart: start})
+			h = &cur.hunks[len(cur.hunks)-1]
+		case h == nil:
+			// Text before the first hunk, such as "diff --git" or "index"
+			// lines, carries nothing to apply.
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the line before.
+			if n := len(h.ops); n > 0 {
+				h.ops[n-1].line = strings.TrimSuffix(h.ops[n-1].line, "\n")
+			}
+		case line == "\n" || line == "":
+			// Editors and models often drop the space of a blank context
+			// line.
+			h.ops = append(h.ops, diffOp{' ', "\n"})
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			text := line[1:]
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			h.ops = append(h.ops, diffOp{line[0], text})
+		default:
+			h = nil
+		}
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no file headers (--- and +++) or %s blocks found in the patch", searchMarker)
+	}
+	for _, e := range edits {
+		for i := range e.hunks {
+			// Blank lines trailing the patch are not context.
+			ops := e.hunks[i].ops
+			for len(ops) > 0 && ops[len(ops)-1] == (diffOp{' ', "\n"}) {
+				ops = ops[:len(ops)-1]
+			}
+			e.hunks[i].ops = ops
+		}
+		if len(e.hunks) == 0 {
+			return nil, fmt.Errorf("no hunks for %s", e.path)
+		}
+	}
+	return edits, nil
+}
+
+// diffPath strips the a/ or b/ prefix and any timestamp from a path in a
+// file header.
+func diffPath(s string) string {
+	s = strings.TrimRight(s, "\r\n")
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return s
+	}
+	for _, prefix := range []string{"a/", "b/"} {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			return rest
+		}
+	}
+	return s
+}
+
+func parseReplaceBlocks(patch, defaultPath string) ([]fileEdit, error) {
+	lines := splitLines(patch)
+	var edits []fileEdit
+	path := defaultPath
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if line != searchMarker {
+			// A lone word such as "internal/x.go" names the file for the
+			// blocks that follow; prose and code fences do not.
+			if t := strings.TrimSpace(line); t != "" && !strings.HasPrefix(t, "```") && !strings.ContainsAny(t, " \t") {
+				path = t
+			}
+			continue
+		}
+		var block replaceBlock
+		section := &block.search
+		closed := false
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			switch strings.TrimRight(l, "\r\n") {
+			case dividerMarker:
+				section = &block.replace
+				continue
+			case replaceMarker:
+				closed = true
+			}
+			if closed {
+				break
+			}
+			if !strings.HasSuffix(l, "\n") {
+				l += "\n"
+			}
+			*section = append(*section, l)
+		}
+		if !closed {
+			return nil, fmt.Errorf("%s block without %s", searchMarker, replaceMarker)
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no file given for a %s block; set path or put the file name on the line before it", searchMarker)
+		}
+		if n := len(edits); n > 0 && edits[n-1].path == path {
+			edits[n-1].blocks = append(edits[n-1].blocks, block)
+		} else {
+			edits = append(edits, fileEdit{path: path, blocks: []replaceBlock{block}})
+		}
+	}
+	return edits, nil
+}
+
+// applyEdit returns content with the edit applied.
+func applyEdit(content string, e fileEdit) (string, error) {
+	lines := splitLines(content)
+	if len(e.hunks) > 0 {
+		return applyHunks(lines, e.hunks)
+	}
+	for i, b := range e.blocks {
+		if len(b.search) == 0 {
+			if len(lines) > 0 {
+				return "", fmt.Errorf("block %d has an empty SEARCH section, which only works for a new file", i+1)
+			}
+			lines = b.replace
+			continue
+		}
+		found := findLines(lines, b.search, 0)
+		switch len(found) {
+		case 0:
+			return "", fmt.Errorf("block %d: the SEARCH lines were not found in %s", i+1, e.path)
+		case 1:
+		default:
+			return "", fmt.Errorf("block %d: the SEARCH lines occur %d times in %s; include more lines to pick one", i+1, len(found), e.path)
+		}
+		lines = splice(lines, found[0], len(b.search), b.replace)
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// applyHunks applies hunks in order. Each is looked for nearest the line
+// its header gives, since models rarely count lines exactly, and after the
+// hunk before it.
+func applyHunks(lines []string, hunks []hunk) (string, error) {
+	from, shift := 0, 0
+	for i, h := range hunks {
+		var old, new []string
+		for _, op := range h.ops {
+			if op.kind != '+' {
+				old = append(old, op.line)
+			}
+			if op.kind != '-' {
+				new = append(new, op.line)
+			}
+		}
+		// A hunk that only adds lines goes after the line it names.
+		at := max(h.oldStart+shift, from)
+		if len(old) > 0 {
+			at = max(h.oldStart-1+shift, from)
+			found := findLines(lines, old, from)
+			if len(found) == 0 {
+				return "", fmt.Errorf("hunk %d (@@ -%d) does not match the file", i+1, h.oldStart)
+			}
+			at = nearest(found, at)
+		}
+		at = min(at, len(lines))
+		lines = splice(lines, at, len(old), new)
+		from = at + len(new)
+		shift += len(new) - len(old)
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// findLines returns every index from on where block occurs in lines,
+// ignoring trailing whitespace.
+func findLines(lines, block []string, from int) []int {
+	var found []int
+	for i := from; i+len(block) <= len(lines); i++ {
+		match := true
+		for j, b := range block {
+			if strings.TrimRight(lines[i+j], " \t\r\n") != strings.TrimRight(b, " \t\r\n") {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+func nearest(found []int, want int) int {
+	best := found[0]
+	for _, f := range found[1:] {
+		if abs(f-want) < abs(best-want) {
+			best = f
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// splice replaces n lines at i with repl.
+func splice(lines []string, i, n int, repl []string) []string {
+	out := make([]string, 0, len(lines)-n+len(repl))
+	out = append(out, lines[:i]...)
+	out = append(out, repl...)
+	return append(out, lines[i+n:]...)
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+const patchBase = "package a\n\nfunc A() int {\n\treturn 1\n}\n\nfunc B() int {\n\treturn 2\n}\n"
+
+func applyPatch(t *testing.T, content, patch, path string) (string, error) {
+	t.Helper()
+	edits, err := parsePatch(patch, path)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range edits {
+		if content, err = applyEdit(content, e); err != nil {
+			return "", err
+		}
+	}
+	return content, nil
+}
+
+func TestApplyUnifiedDiff(t *testing.T) {
+	// The line numbers are off, as they often are from models, and the blank
+	// context line has lost its space.
+	patch := `--- a/a.go
++++ b/a.go
+@@ -5,3 +5,3 @@
+
+ func B() int {
+-	return 2
++	return 3
+ }
+`
+	got, err := applyPatch(t, patchBase, patch, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(patchBase, "return 2", "return 3", 1); got != want {
+		t.Errorf("patched =\n%s", got)
+	}
+
+	if _, err := applyPatch(t, patchBase, strings.Replace(patch, "return 2", "return 9", 1), ""); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("mismatched hunk: %v", err)
+	}
+	if _, err := applyPatch(t, patchBase, "--- a/a.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package a\n", ""); err == nil {
+		t.Error("deleting a file should be refused")
+	}
+	if _, err := applyPatch(t, patchBase, "just some text\n", ""); err == nil {
+		t.Error("a patch without headers should be refused")
+	}
+}
+
+func TestApplyReplaceBlocks(t *testing.T) {
+	patch := "a.go\n<<<<<<< SEARCH\nfunc A() int {\n\treturn 1\n=======\nfunc A() int {\n\treturn 10\n>>>>>>> REPLACE\n"
+	got, err := applyPatch(t, patchBase, patch, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(patchBase, "return 1", "return 10", 1); got != want {
+		t.Errorf("patched =\n%s", got)
+	}
+
+	ambiguous := "<<<<<<< SEARCH\n}\n=======\n};\n>>>>>>> REPLACE\n"
+	if _, err := applyPatch(t, patchBase, ambiguous, "a.go"); err == nil || !strings.Contains(err.Error(), "occur 2 times") {
+		t.Errorf("ambiguous block: %v", err)
+	}
+	if _, err := applyPatch(t, patchBase, ambiguous, ""); err == nil || !strings.Contains(err.Error(), "no file given") {
+		t.Errorf("block without a file: %v", err)
+	}
+	if _, err := applyPatch(t, patchBase, "<<<<<<< SEARCH\nx\n=======\n", "a.go"); err == nil {
+		t.Error("an unterminated block should be refused")
+	}
+}
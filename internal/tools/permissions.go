@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Policy says whether a tool may run when the model calls it.
+type Policy string
+
+const (
+	// PolicyAllow runs the tool without asking.
+	PolicyAllow Policy = "allow"
+	// PolicyAsk asks the user to approve each call.
+	PolicyAsk Policy = "ask"
+	// PolicyDeny never runs the tool; the model is told it was refused.
+	PolicyDeny Policy = "deny"
+)
+
+// ParsePolicy checks a policy name from the config file.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyAllow, PolicyAsk, PolicyDeny:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown tool policy %q (want %s, %s or %s)", s, PolicyAllow, PolicyAsk, PolicyDeny)
+}
+
+// Permissions decides which tool calls may run. Each tool has a policy,
+// falling back to a default for tools without one, and a tool the user has
+// approved for the session runs without asking until ResetSession.
+//
+// A nil *Permissions allows every call.
+type Permissions struct {
+	mu       sync.Mutex
+	fallback Policy
+	policies map[string]Policy
+	session  map[string]bool
+}
+
+func NewPermissions(fallback Policy, policies map[string]Policy) *Permissions {
+	p := &Permissions{fallback: fallback, policies: make(map[string]Policy, len(policies))}
+	for name, policy := range policies {
+		p.policies[name] = policy
+	}
+	return p
+}
+
+// ParsePermissions builds Permissions from the policy names in the config
+// file.
+func ParsePermissions(fallback string, policies map[string]string) (*Permissions, error) {
+	def, err := ParsePolicy(fallback)
+	if err != nil {
+		return nil, err
+	}
+	parsed := make(map[string]Policy, len(policies))
+	for name, s := range policies {
+		if parsed[name], err = ParsePolicy(s); err != nil {
+			return nil, fmt.Errorf("tool %s: %w", name, err)
+		}
+	}
+	return NewPermissions(def, parsed), nil
+}
+
+// Policy returns the policy for the named tool.
+func (p *Permissions) Policy(name string) Policy {
+	if p == nil {
+		return PolicyAllow
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	policy, ok := p.policies[name]
+	if !ok {
+		policy = p.fallback
+	}
+	if policy == PolicyAsk && p.session[name] {
+		return PolicyAllow
+	}
+	return policy
+}
+
+// AllowForSession lets the named tool run without asking until
+// ResetSession. It does not override PolicyDeny.
+func (p *Permissions) AllowForSession(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.session == nil {
+		p.session = make(map[string]bool)
+	}
+	p.session[name] = true
+}
+
+// ResetSession forgets the tools allowed with AllowForSession.
+func (p *Permissions) ResetSession() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.session = nil
+}
@@ -0,0 +1,33 @@
+package tools
+
+import "testing"
+
+func TestPermissions(t *testing.T) {
+	p, err := ParsePermissions("ask", map[string]string{"echo": "allow", "web_search": "deny"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]Policy{"echo": PolicyAllow, "web_search": PolicyDeny, "calculator": PolicyAsk} {
+		if got := p.Policy(name); got != want {
+			t.Errorf("Policy(%s) = %s, want %s", name, got, want)
+		}
+	}
+
+	p.AllowForSession("calculator")
+	p.AllowForSession("web_search")
+	if p.Policy("calculator") != PolicyAllow || p.Policy("web_search") != PolicyDeny {
+		t.Error("allowing for the session should only lift ask")
+	}
+	p.ResetSession()
+	if p.Policy("calculator") != PolicyAsk {
+		t.Error("ResetSession should forget session approvals")
+	}
+
+	if _, err := ParsePermissions("ask", map[string]string{"echo": "sometimes"}); err == nil {
+		t.Error("expected an unknown policy to be rejected")
+	}
+	var none *Permissions
+	if none.Policy("anything") != PolicyAllow {
+		t.Error("nil Permissions should allow everything")
+	}
+}
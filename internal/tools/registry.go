@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Registry holds the tools offered to the model, keyed by name and kept in
+// registration order so requests are stable from turn to turn.
+type Registry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+	order []string
+}
+
+// DefaultRegistry contains the built-in tools. The package-level helpers
+// (Register, GetAvailableTools, ExecuteTool) operate on it.
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, t := range builtinTools() {
+		if err := r.Register(t); err != nil {
+			log.Printf("tools: %v", err)
+		}
+	}
+	return r
+}
+
+func NewRegistry() *Registry {
+	return &Registry{tools: map[string]Tool{}}
+}
+
+// Register adds a tool. Names must be unique; use Unregister first to
+// replace an existing tool.
+func (r *Registry) Register(t Tool) error {
+	name := t.Name()
+	if name == "" {
+		return fmt.Errorf("cannot register a tool without a name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[name]; ok {
+		return fmt.Errorf("tool %q is already registered", name)
+	}
+	r.tools[name] = t
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Unregister removes a tool, reporting whether it was present.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[name]; !ok {
+		return false
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Previewer returns the named tool's Previewer, if it changes files and so
+// must be approved after a preview whatever its policy.
+func (r *Registry) Previewer(name string) (Previewer, bool) {
+	t, ok := r.Get(name)
+	if !ok {
+		return nil, false
+	}
+	p, ok := t.(Previewer)
+	return p, ok
+}
+
+// Names returns the registered tool names in registration order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.order...)
+}
+
+// Tools returns the registered tools in registration order.
+func (r *Registry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		out = append(out, r.tools[name])
+	}
+	return out
+}
+
+// Definitions returns the function-calling definitions of every tool.
+func (r *Registry) Definitions() []Definition {
+	return Definitions(r.Tools())
+}
+
+// Execute runs the named tool with the raw JSON arguments from the model.
+func (r *Registry) Execute(ctx context.Context, name string, params json.RawMessage) (string, error) {
+	t, ok := r.Get(name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	return t.Execute(ctx, params)
+}
+
+// Register adds a tool to DefaultRegistry.
+func Register(t Tool) error {
+	return DefaultRegistry.Register(t)
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type greetParams struct {
+	Name string `json:"name" required:"true"`
+}
+
+func greet(ctx context.Context, p greetParams) (string, error) {
+	return "hello " + p.Name, nil
+}
+
+func TestRegistryRegisterAndExecute(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(New("greet", "Greets someone.", greet)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(New("greet", "again", greet)); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+
+	out, err := r.Execute(context.Background(), "greet", json.RawMessage(`{"name":"clai"}`))
+	if err != nil || out != "hello clai" {
+		t.Fatalf("Execute = %q, %v", out, err)
+	}
+	if _, err := r.Execute(context.Background(), "missing", nil); err == nil || !strings.Contains(err.Error(), "unknown tool") {
+		t.Errorf("expected unknown tool error, got %v", err)
+	}
+	if _, err := r.Execute(context.Background(), "greet", json.RawMessage(`{"name":1}`)); err == nil {
+		t.Error("expected bad params to fail")
+	}
+
+	defs := r.Definitions()
+	if len(defs) != 1 || defs[0].Function.Name != "greet" || defs[0].Function.Parameters.Required[0] != "name" {
+		t.Errorf("unexpected definitions %+v", defs)
+	}
+	if !r.Unregister("greet") || len(r.Names()) != 0 {
+		t.Error("expected greet to be removed")
+	}
+}
+
+func TestDefaultRegistryBuiltins(t *testing.T) {
+	want := []string{"calculator", "echo", "web_search"}
+	names := DefaultRegistry.Names()
+	for _, name := range want {
+		found := false
+		for _, n := range names {
+			found = found || n == name
+		}
+		if !found {
+			t.Errorf("builtin %s missing from %v", name, names)
+		}
+	}
+	out, err := ExecuteTool(context.Background(), "calculator", json.RawMessage(`{"expression":"2 * (3 + 4)"}`))
+	if err != nil || out != "14" {
+		t.Errorf("calculator = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type schemaTestParams struct {
+	Query   string   `json:"query" description:"What to look for" required:"true"`
+	Mode    string   `json:"mode,omitempty" enum:"fast, thorough"`
+	Limit   int      `json:"limit"`
+	Ratio   float64  `json:"ratio"`
+	Tags    []string `json:"tags"`
+	Verbose bool
+	Skipped string `json:"-"`
+	hidden  string
+}
+
+func TestSchemaFor(t *testing.T) {
+	s := SchemaFor(schemaTestParams{})
+	if s.Type != "object" {
+		t.Fatalf("type = %q, want object", s.Type)
+	}
+	wantTypes := map[string]string{
+		"query":   "string",
+		"mode":    "string",
+		"limit":   "integer",
+		"ratio":   "number",
+		"tags":    "array",
+		"Verbose": "boolean",
+	}
+	if len(s.Properties) != len(wantTypes) {
+		t.Fatalf("got %d properties, want %d: %v", len(s.Properties), len(wantTypes), s.Properties)
+	}
+	for name, typ := range wantTypes {
+		if s.Properties[name] == nil || s.Properties[name].Type != typ {
+			t.Errorf("property %s: got %+v, want type %s", name, s.Properties[name], typ)
+		}
+	}
+	if s.Properties["query"].Description != "What to look for" {
+		t.Errorf("missing description: %+v", s.Properties["query"])
+	}
+	if !reflect.DeepEqual(s.Properties["mode"].Enum, []string{"fast", "thorough"}) {
+		t.Errorf("enum = %v", s.Properties["mode"].Enum)
+	}
+	if s.Properties["tags"].Items == nil || s.Properties["tags"].Items.Type != "string" {
+		t.Errorf("tags items = %+v", s.Properties["tags"].Items)
+	}
+	if !reflect.DeepEqual(s.Required, []string{"query"}) {
+		t.Errorf("required = %v", s.Required)
+	}
+}
+
+func TestToolDefinitionJSON(t *testing.T) {
+	tool := New("calculator", "calc", executeCalculator)
+	data, err := json.Marshal(DefinitionOf(tool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["type"] != "function" {
+		t.Errorf("type = %v", got["type"])
+	}
+	fn := got["function"].(map[string]any)
+	params := fn["parameters"].(map[string]any)
+	if fn["name"] != "calculator" || params["type"] != "object" {
+		t.Errorf("unexpected definition %s", data)
+	}
+	props := params["properties"].(map[string]any)
+	if props["expression"].(map[string]any)["type"] != "string" {
+		t.Errorf("unexpected expression schema %s", data)
+	}
+}
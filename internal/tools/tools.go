@@ -1,52 +1,94 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
 
-type Tool struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Parameters  any    `json:"parameters"`
+// Tool is a capability the model can call. Implementations are registered
+// with a Registry; New covers the common case of a typed parameter struct
+// and a plain function.
+type Tool interface {
+	Name() string
+	Description() string
+	Schema() *Schema
+	Execute(ctx context.Context, params json.RawMessage) (string, error)
+}
+
+// New builds a Tool from a function taking a typed parameter struct. The
+// schema is generated from P with SchemaFor, so its struct tags double as
+// the documentation the model sees.
+func New[P any](name, description string, fn func(ctx context.Context, params P) (string, error)) Tool {
+	var zero P
+	return &funcTool[P]{name: name, description: description, schema: SchemaFor(zero), fn: fn}
+}
+
+type funcTool[P any] struct {
+	name        string
+	description string
+	schema      *Schema
+	fn          func(context.Context, P) (string, error)
+}
+
+func (t *funcTool[P]) Name() string        { return t.name }
+func (t *funcTool[P]) Description() string { return t.description }
+func (t *funcTool[P]) Schema() *Schema     { return t.schema }
+
+func (t *funcTool[P]) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p P
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return "", fmt.Errorf("error unmarshalling %s params: %w", t.name, err)
+		}
+	}
+	return t.fn(ctx, p)
+}
+
+// DefinitionOf returns the function-calling definition sent to the model.
+func DefinitionOf(t Tool) Definition {
+	return Definition{
+		Type: "function",
+		Function: FunctionDefinition{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Parameters:  t.Schema(),
+		},
+	}
 }
 
 type CalculatorParams struct {
-	Expression string `json:"expression"`
+	Expression string `json:"expression" description:"Mathematical expression to evaluate, e.g. (2 + 3) * 4" required:"true"`
 }
 
 type EchoParams struct {
-	Message string `json:"message"`
+	Message string `json:"message" description:"Text to echo back" required:"true"`
 }
 
-type WebSearchParams struct {
-	Query string `json:"query"`
+func builtinTools() []Tool {
+	return append([]Tool{
+		New("calculator", "A simple calculator that evaluates a mathematical expression.", executeCalculator),
+		New("echo", "Echoes the message back to the user.", executeEcho),
+		New("web_search", "Searches the web and returns the title, URL and a snippet of each top result.", executeWebSearch),
+	}, append(append(fsTools(), editTools()...), commandTools()...)...)
 }
 
-var availableTools = []Tool{
-	{
-		Name:        "calculator",
-		Description: "A simple calculator that evaluates a mathematical expression.",
-		Parameters:  CalculatorParams{},
-	},
-	{
-		Name:        "echo",
-		Description: "Echoes the message back to the user.",
-		Parameters:  EchoParams{},
-	},
-	{
-		Name:        "web_search",
-		Description: "Performs a web search for the given query.",
-		Parameters:  WebSearchParams{},
-	},
+// GetAvailableTools returns the tools in DefaultRegistry.
+func GetAvailableTools() []Tool {
+	return DefaultRegistry.Tools()
 }
 
-func GetAvailableTools() []Tool {
-	return availableTools
+// Definitions converts tools to the definitions sent in a chat request.
+func Definitions(toolList []Tool) []Definition {
+	defs := make([]Definition, 0, len(toolList))
+	for _, t := range toolList {
+		defs = append(defs, DefinitionOf(t))
+	}
+	return defs
 }
 
 func GetAvailableToolsJSON() (string, error) {
-	toolsJSON, err := json.Marshal(availableTools)
+	toolsJSON, err := json.Marshal(DefaultRegistry.Definitions())
 	if err != nil {
 		return "", fmt.Errorf("error marshalling tools: %w", err)
 	}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WebSearchParams struct {
+	Query string `json:"query" description:"Search query" required:"true"`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of results, up to the configured limit"`
+}
+
+// SearchEngine is the backend of web_search: a SearXNG instance's /search
+// URL, or any endpoint that takes the same q and format=json parameters
+// and answers with the same JSON:
+//
+//	{"results": [{"title": "...", "url": "https://...", "content": "snippet"}]}
+type SearchEngine struct {
+	// URL is the search endpoint, e.g. http://localhost:8888/search. Query
+	// parameters already in it, such as categories or language, are kept.
+	URL string
+	// Timeout bounds each search.
+	Timeout time.Duration
+	// MaxResults caps the results returned to the model.
+	MaxResults int
+}
+
+const (
+	defaultSearchTimeout = 10 * time.Second
+	defaultSearchResults = 5
+	// maxSearchResponse is the largest response body read from the engine.
+	maxSearchResponse = 2 << 20
+	// maxSnippetLength shortens long result snippets.
+	maxSnippetLength = 300
+)
+
+var (
+	searchMu     sync.Mutex
+	searchEngine = SearchEngine{Timeout: defaultSearchTimeout, MaxResults: defaultSearchResults}
+)
+
+// SetSearchEngine configures the built-in web_search tool. Zero limits are
+// given their defaults.
+func SetSearchEngine(e SearchEngine) {
+	if e.Timeout <= 0 {
+		e.Timeout = defaultSearchTimeout
+	}
+	if e.MaxResults <= 0 {
+		e.MaxResults = defaultSearchResults
+	}
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	searchEngine = e
+}
+
+func currentSearchEngine() SearchEngine {
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	return searchEngine
+}
+
+func executeWebSearch(ctx context.Context, params WebSearchParams) (string, error) {
+	return currentSearchEngine().Search(ctx, params)
+}
+
+// SearchResult is one hit in the engine's JSON answer.
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content"`
+}
+
+// Search queries the engine and formats up to the result limit as a
+// numbered list of titles, URLs and snippets.
+func (e SearchEngine) Search(ctx context.Context, params WebSearchParams) (string, error) {
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		return "", errors.New("no search query given")
+	}
+	if e.URL == "" {
+		return "", errors.New("web search is not configured; set tools.search.url to a SearXNG /search URL")
+	}
+	results, err := e.fetch(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	limit := e.MaxResults
+	if params.Limit > 0 && params.Limit < limit {
+		limit = params.Limit
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range results {
+		if n == limit {
+			break
+		}
+		if r.URL == "" {
+			continue
+		}
+		n++
+		title := strings.TrimSpace(r.Title)
+		if title == "" {
+			title = r.URL
+		}
+		fmt.Fprintf(&b, "%d. %s\n   %s\n", n, title, r.URL)
+		if snippet := strings.Join(strings.Fields(r.Content), " "); snippet != "" {
+			fmt.Fprintf(&b, "   %s\n", shorten(snippet, maxSnippetLength))
+		}
+	}
+	if n == 0 {
+		return fmt.Sprintf("No results for %q.", query), nil
+	}
+	return b.String(), nil
+}
+
+func (e SearchEngine) fetch(ctx context.Context, query string) ([]SearchResult, error) {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return nil, fmt.Errorf("search URL %s: %w", e.URL, err)
+	}
+	q := u.Query()
+	q.Set("q", query)
+	q.Set("format", "json")
+	u.RawQuery = q.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("search timed out after %s", e.Timeout)
+		}
+		return nil, fmt.Errorf("failed to connect to the search engine at %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		// SearXNG answers 403 when the json format is not enabled.
+		return nil, fmt.Errorf("search engine refused the request (%s); a SearXNG instance must list json under search.formats", resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("search engine returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var body struct {
+		Results []SearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSearchResponse)).Decode(&body); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("search timed out after %s", e.Timeout)
+		}
+		return nil, fmt.Errorf("error decoding search results: %w", err)
+	}
+	return body.Results, nil
+}
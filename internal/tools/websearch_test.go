@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSearchServer stands in for a SearXNG instance, answering every search
+// with results and recording the query it was sent.
+func newSearchServer(t *testing.T, results []SearchResult, query *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+			http.NotFound(w, r)
+			return
+		}
+		if query != nil {
+			*query = r.URL.RawQuery
+		}
+		json.NewEncoder(w).Encode(map[string]any{"query": r.URL.Query().Get("q"), "results": results})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSearch(t *testing.T) {
+	results := []SearchResult{
+		{Title: "Go", URL: "https://go.dev/", Content: "Build simple,\n  secure, scalable systems with Go."},
+		{Title: "", URL: "https://pkg.go.dev/"},
+		{Title: "No URL", Content: "skipped"},
+		{Title: "Tour", URL: "https://go.dev/tour/", Content: strings.Repeat("x", 400)},
+	}
+	var query string
+	srv := newSearchServer(t, results, &query)
+	e := SearchEngine{URL: srv.URL + "/search?language=en", Timeout: time.Second, MaxResults: 5}
+
+	out, err := e.Search(context.Background(), WebSearchParams{Query: "golang tour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "q=golang+tour") || !strings.Contains(query, "language=en") {
+		t.Errorf("engine was sent %q", query)
+	}
+	want := "1. Go\n   https://go.dev/\n   Build simple, secure, scalable systems with Go.\n2. https://pkg.go.dev/\n   https://pkg.go.dev/\n3. Tour\n   https://go.dev/tour/\n   " + strings.Repeat("x", maxSnippetLength) + "…\n"
+	if out != want {
+		t.Errorf("Search =\n%s\nwant\n%s", out, want)
+	}
+
+	out, err = e.Search(context.Background(), WebSearchParams{Query: "go", Limit: 1})
+	if err != nil || strings.Contains(out, "2.") {
+		t.Errorf("limit ignored: %q, %v", out, err)
+	}
+	e.MaxResults = 2
+	if out, _ := e.Search(context.Background(), WebSearchParams{Query: "go", Limit: 10}); strings.Contains(out, "3.") {
+		t.Errorf("limit above the configured maximum: %q", out)
+	}
+
+	empty := SearchEngine{URL: newSearchServer(t, nil, nil).URL + "/search", Timeout: time.Second, MaxResults: 5}
+	if out, err := empty.Search(context.Background(), WebSearchParams{Query: "nothing"}); err != nil || out != `No results for "nothing".` {
+		t.Errorf("no results = %q, %v", out, err)
+	}
+}
+
+func TestWebSearchErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := (SearchEngine{}).Search(ctx, WebSearchParams{Query: "go"}); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("unconfigured engine: %v", err)
+	}
+	if _, err := (SearchEngine{URL: "http://unused/search"}).Search(ctx, WebSearchParams{Query: " "}); err == nil {
+		t.Error("expected an empty query to fail")
+	}
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"403 Forbidden": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "format not allowed", http.StatusForbidden)
+		},
+		"502 Bad Gateway": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "upstream down", http.StatusBadGateway)
+		},
+		"decoding": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>not json</html>"))
+		},
+		"timed out after 50ms": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		},
+	} {
+		srv := httptest.NewServer(handler)
+		e := SearchEngine{URL: srv.URL, Timeout: 50 * time.Millisecond, MaxResults: 5}
+		if _, err := e.Search(ctx, WebSearchParams{Query: "go"}); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: got %v", name, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestWebSearchTool(t *testing.T) {
+	srv := newSearchServer(t, []SearchResult{{Title: "Go", URL: "https://go.dev/"}}, nil)
+	SetSearchEngine(SearchEngine{URL: srv.URL + "/search"})
+	t.Cleanup(func() { SetSearchEngine(SearchEngine{}) })
+	out, err := ExecuteTool(context.Background(), "web_search", json.RawMessage(`{"query":"go"}`))
+	if err != nil || out != "1. Go\n   https://go.dev/\n" {
+		t.Errorf("web_search = %q, %v", out, err)
+	}
+}
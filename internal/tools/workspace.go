@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrOutsideWorkspace is returned for paths that lead out of the workspace,
+// whether by "..", an absolute path or a symbolic link.
+var ErrOutsideWorkspace = errors.New("path is outside the workspace")
+
+// Workspace is the directory tree the filesystem tools are confined to.
+type Workspace struct {
+	// root is absolute with every symbolic link resolved, so resolved paths
+	// can be checked against it by prefix.
+	root string
+
+	mu sync.Mutex
+	// journal holds the edits made through the workspace, oldest first.
+	journal []Edit
+}
+
+// NewWorkspace returns a workspace rooted at dir, which must exist.
+func NewWorkspace(dir string) (*Workspace, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s: %w", dir, err)
+	}
+	root, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s: %w", dir, err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("workspace %s is not a directory", dir)
+	}
+	return &Workspace{root: root}, nil
+}
+
+func (w *Workspace) Root() string {
+	return w.root
+}
+
+// Resolve maps a path given by the model, relative to the root or absolute,
+// to the real path of what it names, following symbolic links. It fails
+// with ErrOutsideWorkspace unless that is inside the workspace. The path
+// need not exist.
+func (w *Workspace) Resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	joined := filepath.Clean(path)
+	if !filepath.IsAbs(joined) {
+		joined = filepath.Join(w.root, joined)
+	}
+	real, err := w.realPath(joined)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if !w.contains(real) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideWorkspace)
+	}
+	return real, nil
+}
+
+// realPath resolves the symbolic links in path. Of a path that does not
+// exist, the deepest existing ancestor is resolved and the rest appended,
+// so a link to the outside is caught even when it is not the last element.
+func (w *Workspace) realPath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return real, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	realParent, err := w.realPath(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(realParent, filepath.Base(path)), nil
+}
+
+func (w *Workspace) contains(path string) bool {
+	return path == w.root || strings.HasPrefix(path, w.root+string(filepath.Separator))
+}
+
+// Rel returns path relative to the root, with forward slashes, for showing
+// to the model.
+func (w *Workspace) Rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+var (
+	workspaceMu      sync.Mutex
+	defaultWorkspace *Workspace
+)
+
+// SetWorkspace confines the built-in filesystem tools to dir. Until it is
+// called they work in the current directory.
+func SetWorkspace(dir string) error {
+	w, err := NewWorkspace(dir)
+	if err != nil {
+		return err
+	}
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	defaultWorkspace = w
+	return nil
+}
+
+// CurrentWorkspace returns the workspace of the built-in filesystem tools.
+func CurrentWorkspace() (*Workspace, error) {
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	if defaultWorkspace == nil {
+		w, err := NewWorkspace(".")
+		if err != nil {
+			return nil, err
+		}
+		defaultWorkspace = w
+	}
+	return defaultWorkspace, nil
+}
@@ -0,0 +1,254 @@
+package ui
+
+import (
+	"bytes"
+	"clai/internal/llm"
+	"clai/internal/tools"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// argsEditedMsg carries tool call arguments edited in the external editor.
+type argsEditedMsg struct {
+	text string
+	err  error
+}
+
+// runNextToolCall runs the first queued tool call as its policy says: at
+// once, after the user approves it in a modal, or not at all. A call that
+// changes files is always shown as a diff for approval first. A call with
+// malformed arguments is answered with the error, without asking.
+func (c *ChatModel) runNextToolCall() tea.Cmd {
+	call := c.pendingToolCalls[0]
+	if call.ArgumentsError() != nil {
+		return toolMessageCmd(c.toolCtx, c.LlmClient.RunToolCall(c.toolCtx, call))
+	}
+	policy := c.LlmClient.Permissions().Policy(call.Name)
+	if policy == tools.PolicyDeny {
+		return toolMessageCmd(c.toolCtx, llm.DeniedToolCall(call, "the tool policy forbids it"))
+	}
+	c.preview = ""
+	if _, ok := c.LlmClient.Registry().Previewer(call.Name); ok {
+		diff, err := c.previewCall(call)
+		if err != nil {
+			log.Printf("tool %s failed: %v", call.Name, err)
+			return toolMessageCmd(c.toolCtx, llm.Message{Role: "tool", Content: fmt.Sprintf("error: %v", err), ToolName: call.Name})
+		}
+		c.preview = diff
+		c.approving = true
+		return nil
+	}
+	if policy == tools.PolicyAsk {
+		c.approving = true
+		return nil
+	}
+	return runToolCallCmd(c.toolCtx, c.LlmClient, call)
+}
+
+// previewCall returns the diff a call to a tool that changes files would
+// make.
+func (c *ChatModel) previewCall(call llm.ToolCall) (string, error) {
+	p, ok := c.LlmClient.Registry().Previewer(call.Name)
+	if !ok {
+		return "", fmt.Errorf("tool %s does not change files", call.Name)
+	}
+	return p.Preview(c.toolCtx, call.Parameters)
+}
+
+func toolMessageCmd(ctx context.Context, msg llm.Message) tea.Cmd {
+	return func() tea.Msg {
+		return ToolResultMsg{ToolName: msg.ToolName, Result: msg.Content, ctx: ctx}
+	}
+}
+
+// approve runs the call waiting for approval; forSession lets the tool run
+// without asking for the rest of the session. Tools that change files are
+// approved one diff at a time, and if the files have changed since the diff
+// was shown the new one is shown instead.
+func (c *ChatModel) approve(forSession bool) tea.Cmd {
+	if !c.approving {
+		return nil
+	}
+	call := c.pendingToolCalls[0]
+	if c.preview != "" {
+		if forSession {
+			return nil
+		}
+		diff, err := c.previewCall(call)
+		if err != nil {
+			return errorCmd(err)
+		}
+		if diff != c.preview {
+			c.preview = diff
+			return errorCmd(errors.New("the files changed since the diff was shown; review it again"))
+		}
+	}
+	c.approving = false
+	c.preview = ""
+	if forSession {
+		c.LlmClient.Permissions().AllowForSession(call.Name)
+	}
+	return runToolCallCmd(c.toolCtx, c.LlmClient, call)
+}
+
+// deny tells the model the user refused the call waiting for approval.
+func (c *ChatModel) deny() tea.Cmd {
+	if !c.approving {
+		return nil
+	}
+	c.approving = false
+	c.preview = ""
+	return toolMessageCmd(c.toolCtx, llm.DeniedToolCall(c.pendingToolCalls[0], "the user denied it"))
+}
+
+// setToolCallArgs replaces the arguments of the call waiting for approval,
+// in the queue and in the assistant message that made it, so the model
+// sees the call that actually ran.
+func (c *ChatModel) setToolCallArgs(args json.RawMessage) {
+	c.pendingToolCalls[0].Parameters = args
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		calls := c.Messages[i].ToolCalls
+		if c.Messages[i].Role != "assistant" || len(calls) == 0 {
+			continue
+		}
+		if j := len(calls) - len(c.pendingToolCalls); j >= 0 {
+			calls[j].Parameters = args
+			if i < len(c.List.Items()) {
+				c.List.SetItem(i, Item(itemText(c.Messages[i])))
+			}
+		}
+		return
+	}
+}
+
+// handleApprovalKey answers the approval modal: y or enter runs the call
+// once, a runs it and stops asking about the tool for the session, e edits
+// the arguments first and n or esc refuses it.
+func (m *Model) handleApprovalKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c":
+		return tea.Quit
+	case "y", "enter":
+		return m.Chat.approve(false)
+	case "a":
+		return m.Chat.approve(true)
+	case "n", "esc":
+		return m.Chat.deny()
+	case "e":
+		args := prettyArgs(m.Chat.pendingToolCalls[0].Parameters)
+		return editFileCmd(args, "clai-args-*.json", func(path string, err error) tea.Msg {
+			text, err := readEdited(path, err)
+			return argsEditedMsg{text: text, err: err}
+		})
+	}
+	return nil
+}
+
+// handleArgsEdited takes the edited arguments back to the approval modal.
+func (m *Model) handleArgsEdited(msg argsEditedMsg) tea.Cmd {
+	if !m.Chat.approving {
+		return nil
+	}
+	if msg.err != nil {
+		return errorCmd(msg.err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(msg.text)); err != nil {
+		return errorCmd(fmt.Errorf("edited arguments are not valid JSON: %w", err))
+	}
+	args := json.RawMessage(compact.Bytes())
+	if m.Chat.preview != "" {
+		call := m.Chat.pendingToolCalls[0]
+		call.Parameters = args
+		diff, err := m.Chat.previewCall(call)
+		if err != nil {
+			return errorCmd(fmt.Errorf("edited arguments: %w", err))
+		}
+		m.Chat.preview = diff
+	}
+	m.Chat.setToolCallArgs(args)
+	return nil
+}
+
+// prettyArgs indents a call's JSON arguments for display, leaving anything
+// that is not valid JSON as it is.
+func prettyArgs(args json.RawMessage) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, args, "", "  "); err != nil {
+		return string(args)
+	}
+	return out.String()
+}
+
+func (m *Model) approvalView() string {
+	call := m.Chat.pendingToolCalls[0]
+	width := max(m.Width*2/3, 20)
+	height := max(m.Height/2, 3)
+	question := fmt.Sprintf("Run tool %s?", call.Name)
+	body := lipgloss.NewStyle().Width(width - 4).MaxHeight(height).
+		Render(prettyArgs(call.Parameters))
+	hint := "y allow once · a allow for this session · e edit arguments · n deny"
+	if m.Chat.preview != "" {
+		question = fmt.Sprintf("Apply these changes with %s?", call.Name)
+		body = m.diffView(m.Chat.preview, width-4, height)
+		hint = "y apply · e edit arguments · n deny"
+	}
+	title := lipgloss.NewStyle().Bold(true).Foreground(m.Theme.Accent1).Render(question)
+	keys := lipgloss.NewStyle().Foreground(m.Theme.Accent2).Faint(true).Render(hint)
+	parts := []string{title, "", body, "", keys}
+	// The modal hides the error banner, so show a rejected edit here.
+	if m.ShowError && m.ErrorMessage != "" {
+		parts = append(parts, "", m.ErrorBanner.Width(width-4).Render(m.ErrorMessage))
+	}
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.Theme.Accent1).
+		Padding(0, 1).
+		Width(width).
+		Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+	return lipgloss.Place(m.Width, m.Height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// diffView colours a unified diff, cutting it to height lines.
+func (m *Model) diffView(diff string, width, height int) string {
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	header := lipgloss.NewStyle().Bold(true)
+	hunk := lipgloss.NewStyle().Foreground(m.Theme.Accent2)
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	more := 0
+	if len(lines) > height {
+		more = len(lines) - (height - 1)
+		lines = lines[:height-1]
+	}
+	out := make([]string, 0, len(lines)+1)
+	for _, l := range lines {
+		l = ansi.Truncate(strings.ReplaceAll(l, "\t", "    "), width, "…")
+		switch {
+		case strings.HasPrefix(l, "+++ "), strings.HasPrefix(l, "--- "):
+			l = header.Render(l)
+		case strings.HasPrefix(l, "@@"):
+			l = hunk.Render(l)
+		case strings.HasPrefix(l, "+"):
+			l = added.Render(l)
+		case strings.HasPrefix(l, "-"):
+			l = removed.Render(l)
+		}
+		out = append(out, l)
+	}
+	if more > 0 {
+		out = append(out, lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("… %d more lines", more)))
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"clai/internal/tools"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// callTool has the model ask for a tool during a turn of m's chat.
+func callTool(m *Model, name, args string) ToolResultMsg {
+	m.Chat.Send("go")
+	call := llm.ToolCall{Name: name, Parameters: json.RawMessage(args)}
+	cmd := m.Chat.finishRound(llm.Response{Message: llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{call}}})
+	if cmd == nil {
+		return ToolResultMsg{}
+	}
+	result, _ := cmd().(ToolResultMsg)
+	return result
+}
+
+func TestApprovalModal(t *testing.T) {
+	m := newCommandModel(t)
+	m.Width, m.Height = 100, 40
+	m.Chat.LlmClient.SetPermissions(tools.NewPermissions(tools.PolicyAsk, map[string]tools.Policy{
+		"calculator": tools.PolicyDeny,
+	}))
+
+	if got := callTool(m, "echo", `{"message":"hi"}`); got.ToolName != "" || !m.Chat.approving {
+		t.Fatalf("echo should wait for approval, got %+v", got)
+	}
+	if view := m.View(); !strings.Contains(view, "Run tool echo?") || !strings.Contains(view, `"message": "hi"`) {
+		t.Errorf("modal not shown:\n%s", view)
+	}
+
+	m.Update(argsEditedMsg{text: "{not json"})
+	if m.Chat.pendingToolCalls[0].Parameters == nil || string(m.Chat.pendingToolCalls[0].Parameters) != `{"message":"hi"}` {
+		t.Error("invalid edited arguments should be rejected")
+	}
+	m.Update(argsEditedMsg{text: "{\n  \"message\": \"edited\"\n}\n"})
+	result := typeRunes(m, "y")().(ToolResultMsg)
+	if result.Result != "edited" || m.Chat.approving {
+		t.Errorf("approved call ran with %+v", result)
+	}
+	if args := string(m.Chat.Messages[len(m.Chat.Messages)-1].ToolCalls[0].Parameters); args != `{"message":"edited"}` {
+		t.Errorf("transcript keeps the original arguments %s", args)
+	}
+	m.Chat.StopStreaming()
+
+	callTool(m, "echo", `{"message":"again"}`)
+	result = typeRunes(m, "n")().(ToolResultMsg)
+	if !strings.Contains(result.Result, "the user denied it") {
+		t.Errorf("denial = %q", result.Result)
+	}
+	m.Chat.handleToolResult(result)
+	if last := m.Chat.Messages[len(m.Chat.Messages)-1]; last.Role != "tool" || last.Content != result.Result {
+		t.Errorf("denial not fed back to the model: %+v", last)
+	}
+	m.Chat.StopStreaming()
+
+	callTool(m, "echo", `{"message":"a"}`)
+	typeRunes(m, "a")
+	m.Chat.StopStreaming()
+	if got := callTool(m, "echo", `{"message":"b"}`); got.Result != "b" || m.Chat.approving {
+		t.Errorf("echo allowed for the session should run without asking, got %+v", got)
+	}
+	m.Chat.StopStreaming()
+
+	if got := callTool(m, "calculator", `{"expression":"1+1"}`); !strings.Contains(got.Result, "policy forbids") {
+		t.Errorf("denied tool ran: %+v", got)
+	}
+	m.Chat.StopStreaming()
+
+	m.Chat.Clear()
+	callTool(m, "echo", `{"message":"c"}`)
+	if !m.Chat.approving {
+		t.Error("session approvals should not survive /clear")
+	}
+}
+
+func TestFileEditsShowADiffAndUndo(t *testing.T) {
+	prev, err := tools.CurrentWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tools.SetWorkspace(prev.Root()) })
+	dir := t.TempDir()
+	if err := tools.SetWorkspace(dir); err != nil {
+		t.Fatal(err)
+	}
+	m := newCommandModel(t)
+	m.Width, m.Height = 100, 40
+	// Even an allowed tool that changes files waits for the diff to be
+	// approved.
+	m.Chat.LlmClient.SetPermissions(tools.NewPermissions(tools.PolicyAllow, nil))
+	file := filepath.Join(dir, "hello.txt")
+
+	callTool(m, "write_file", `{"path":"hello.txt","content":"hello\n"}`)
+	if !m.Chat.approving {
+		t.Fatal("write_file should wait for approval")
+	}
+	if view := m.View(); !strings.Contains(view, "Apply these changes with write_file?") || !strings.Contains(view, "+hello") || strings.Contains(view, "allow for this session") {
+		t.Errorf("diff not shown:\n%s", view)
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Fatal("file written before approval")
+	}
+	if typeRunes(m, "a"); !m.Chat.approving {
+		t.Error("changes to files cannot be approved for the session")
+	}
+
+	m.Update(argsEditedMsg{text: `{"path":"../escape.txt","content":"x"}`})
+	if !strings.Contains(m.Chat.preview, "+hello") {
+		t.Error("arguments leaving the workspace should be rejected")
+	}
+	m.Update(argsEditedMsg{text: `{"path":"hello.txt","content":"hello, world\n"}`})
+	if !strings.Contains(m.View(), "+hello, world") {
+		t.Error("diff not updated for the edited arguments")
+	}
+	result := typeRunes(m, "y")().(ToolResultMsg)
+	if result.Result != "created hello.txt (+1 -0)" {
+		t.Errorf("approved write = %q", result.Result)
+	}
+	m.Chat.StopStreaming()
+	if data, err := os.ReadFile(file); err != nil || string(data) != "hello, world\n" {
+		t.Fatalf("file holds %q, %v", data, err)
+	}
+
+	if n, ok := enter(m, "/undo")().(noticeMsg); !ok || n.text != "Undid write_file: hello.txt" {
+		t.Errorf("/undo = %+v", n)
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Error("/undo left the created file")
+	}
+
+	callTool(m, "apply_patch", `{"patch":"nothing to apply"}`)
+	if m.Chat.approving {
+		t.Error("a patch that cannot be applied should go straight back to the model")
+	}
+}
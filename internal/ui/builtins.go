@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"clai/internal/session"
+	"clai/internal/tools"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// init registers the built-in commands. It is not part of DefaultCommands'
+// initializer because /help reads DefaultCommands.
+func init() {
+	for _, cmd := range []Command{
+		{Name: "help", Help: "list the commands", Run: runHelp},
+		{Name: "model", Args: "[name]", Help: "switch model, or pick one from a list", Run: runModel, Complete: completeModel},
+		{Name: "system", Args: "[prompt]", Help: "show or replace the system prompt", Run: runSystem},
+		{Name: "set", Args: "<option> [value]", Help: "change a generation option", Run: (*Model).setOption, Complete: completeOption},
+		{Name: "clear", Help: "start a new conversation", Run: runClear},
+		{Name: "retry", Help: "regenerate the last reply", Run: runRetry},
+		{Name: "edit", Args: "[n]", Help: "edit your nth last message in $EDITOR", Run: runEdit},
+		{Name: "save", Args: "[file]", Help: "save the conversation, or a copy of it to file", Run: runSave},
+		{Name: "load", Args: "[id|file]", Help: "load a session or saved file, or browse sessions", Run: runLoad, Complete: completeSession},
+		{Name: "export", Args: "[file]", Help: "write the conversation as Markdown", Run: runExport},
+		{Name: "tools", Help: "list the tools offered to the model", Run: runTools},
+		{Name: "undo", Args: "[n]", Help: "revert the last n file edits made by tools", Run: runUndo},
+	} {
+		if err := DefaultCommands.Register(cmd); err != nil {
+			log.Printf("commands: %v", err)
+		}
+	}
+}
+
+func errorCmd(err error) tea.Cmd {
+	return func() tea.Msg { return errorMsg{err} }
+}
+
+func runHelp(m *Model, _ string) tea.Cmd {
+	return notice(m.commands().Help() + "\n// at the start sends a message beginning with /")
+}
+
+func runModel(m *Model, name string) tea.Cmd {
+	if name == "" {
+		return listModelsCmd(m.Chat.LlmClient)
+	}
+	m.switchModel(name)
+	return notice("Switched to " + name)
+}
+
+// completeModel offers the models seen the last time they were listed.
+func completeModel(m *Model, _ string) []string {
+	return m.modelNames
+}
+
+func completeOption(*Model, string) []string {
+	return llm.OptionNames()
+}
+
+func runSystem(m *Model, prompt string) tea.Cmd {
+	if prompt == "" {
+		return notice("System prompt:\n" + m.Chat.LlmClient.SystemPrompt())
+	}
+	m.Chat.LlmClient.SetSystemPrompt(prompt)
+	m.Chat.saveSessionInfo()
+	return notice("System prompt replaced")
+}
+
+func runClear(m *Model, _ string) tea.Cmd {
+	m.Chat.Clear()
+	m.updateStatusBar()
+	return nil
+}
+
+func runRetry(m *Model, _ string) tea.Cmd {
+	cmd, err := m.Chat.Retry()
+	if err != nil {
+		return errorCmd(err)
+	}
+	m.updateStatusBar()
+	return cmd
+}
+
+// runSave writes any unsaved messages to the session store, or, given a
+// file name, writes a copy of the conversation there.
+func runSave(m *Model, path string) tea.Cmd {
+	c := &m.Chat
+	if path == "" {
+		if c.Sessions == nil {
+			return errorCmd(errors.New("session storage is disabled; give a file name"))
+		}
+		c.saveSession()
+		if c.Session == nil {
+			return errorCmd(errors.New("nothing to save yet"))
+		}
+		return notice("Saved as session " + c.Session.ID)
+	}
+	sess := session.Session{
+		Info: session.Info{
+			Model:        c.LlmClient.Model(),
+			Host:         c.LlmClient.Host(),
+			SystemPrompt: c.LlmClient.SystemPrompt(),
+			CreatedAt:    time.Now(),
+		},
+		Messages: c.Messages,
+	}
+	if c.Session != nil {
+		sess.Info = c.Session.Info
+	}
+	if err := sess.WriteFile(path); err != nil {
+		return errorCmd(err)
+	}
+	return notice("Saved to " + path)
+}
+
+// runLoad opens a stored session by ID, or a file written by /save. With
+// no argument it opens the session browser.
+func runLoad(m *Model, arg string) tea.Cmd {
+	if arg == "" {
+		return m.openSessionBrowser()
+	}
+	if _, err := os.Stat(arg); err == nil {
+		sess, err := session.ReadFile(arg)
+		if err != nil {
+			return errorCmd(err)
+		}
+		m.Chat.ImportSession(sess)
+	} else {
+		if m.Chat.Sessions == nil {
+			return errorCmd(errors.New("session storage is disabled"))
+		}
+		sess, err := m.Chat.Sessions.Load(arg)
+		if err != nil {
+			return errorCmd(err)
+		}
+		m.Chat.LoadSession(sess)
+	}
+	m.updateStatusBar()
+	return nil
+}
+
+func completeSession(m *Model, _ string) []string {
+	if m.Chat.Sessions == nil {
+		return nil
+	}
+	summaries, err := m.Chat.Sessions.List()
+	if err != nil {
+		log.Printf("session: %v", err)
+		return nil
+	}
+	ids := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// runExport writes the transcript as Markdown, by default to
+// clai-<session>.md in the working directory.
+func runExport(m *Model, path string) tea.Cmd {
+	if path == "" {
+		id := session.NewID(time.Now())
+		if m.Chat.Session != nil {
+			id = m.Chat.Session.ID
+		}
+		path = "clai-" + id + ".md"
+	}
+	if err := os.WriteFile(path, []byte(m.Chat.Markdown()), 0o644); err != nil {
+		return errorCmd(fmt.Errorf("exporting conversation: %w", err))
+	}
+	return notice("Exported to " + path)
+}
+
+func runTools(m *Model, _ string) tea.Cmd {
+	list := m.Chat.LlmClient.Registry().Tools()
+	if len(list) == 0 {
+		return notice("No tools are enabled")
+	}
+	permissions := m.Chat.LlmClient.Permissions()
+	lines := make([]string, 0, len(list))
+	for _, t := range list {
+		lines = append(lines, fmt.Sprintf("%-16s %-6s %s", t.Name(), permissions.Policy(t.Name()), t.Description()))
+	}
+	return notice(strings.Join(lines, "\n"))
+}
+
+// runUndo reverts the last n edits made by write_file and apply_patch,
+// newest first.
+func runUndo(m *Model, arg string) tea.Cmd {
+	n := 1
+	if arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+			return errorCmd(fmt.Errorf("usage: /undo [n], where n is the number of edits to revert"))
+		}
+	}
+	w, err := tools.CurrentWorkspace()
+	if err != nil {
+		return errorCmd(err)
+	}
+	undone, err := w.Undo(n)
+	lines := make([]string, 0, len(undone))
+	for _, e := range undone {
+		paths := make([]string, 0, len(e.Changes))
+		for _, c := range e.Changes {
+			paths = append(paths, c.Path)
+		}
+		lines = append(lines, fmt.Sprintf("Undid %s: %s", e.Tool, strings.Join(paths, ", ")))
+	}
+	switch {
+	case err != nil && len(lines) > 0:
+		return tea.Batch(notice(strings.Join(lines, "\n")), errorCmd(err))
+	case err != nil:
+		return errorCmd(err)
+	case len(lines) == 0:
+		return notice("No edits to undo")
+	}
+	return notice(strings.Join(lines, "\n"))
+}
@@ -1,30 +1,94 @@
 package ui
 
 import (
+	"clai/internal/history"
 	"clai/internal/llm"
+	"clai/internal/session"
+	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type ChatModel struct {
-	Messages      []llm.Message
-	TextInput     textinput.Model
-	Viewport      viewport.Model
-	List          list.Model
-	LlmClient     *llm.Client
-	Spinner       spinner.Model
-	Streaming     bool
-	Width         int
-	Height        int
-	AssistantName string
-	Theme         *Theme
+	Messages []llm.Message
+	Input    textarea.Model
+	// InputMaxHeight is how many rows Input grows to; 0 means
+	// DefaultInputMaxHeight.
+	InputMaxHeight int
+	Viewport       viewport.Model
+	List           list.Model
+	LlmClient      *llm.Client
+	Spinner        spinner.Model
+	Streaming      bool
+	Width          int
+	Height         int
+	AssistantName  string
+	Theme          *Theme
+	// MaxToolIterations caps the rounds of tool calls per user message.
+	MaxToolIterations int
+	// Sessions saves the conversation as it grows; nil disables saving.
+	Sessions *session.Store
+	Session  *session.Session
+	// TurnUsage tallies the responses to the latest user message, and
+	// SessionUsage every response in the session, by model.
+	TurnUsage    llm.Usage
+	SessionUsage map[string]llm.Usage
+	// ContextStrategy is llm.ContextSummarize to have the model summarise
+	// turns that no longer fit the context window; otherwise they are
+	// dropped.
+	ContextStrategy string
+	// History holds the prompts recalled with up/down and ctrl+r; nil
+	// disables recall. HistoryScope is history.ScopeSession to recall only
+	// the current session's prompts.
+	History      *history.History
+	HistoryScope string
+
+	stream           <-chan llm.StreamEvent
+	toolCtx          context.Context
+	cancel           context.CancelFunc
+	pendingToolCalls []llm.ToolCall
+	toolIterations   int
+	saved            int
+	// greeting counts the messages before the session's first, which are
+	// never written.
+	greeting int
+	markdown *markdownRenderer
+	// summary stands in for the first summarized messages when talking to
+	// the model; the transcript itself keeps them.
+	summary    llm.Message
+	summarized int
+	compacting context.Context
+	// recall is the history being browsed with up/down, recallIndex the
+	// prompt on show and draft the input from before browsing began.
+	recall      []string
+	recallIndex int
+	draft       string
+	search      *historySearch
+	// approving is set while the first pending tool call waits for the
+	// user's approval.
+	approving bool
+	// preview is the diff of the call waiting for approval, if it changes
+	// files.
+	preview string
+	// toolOutput is what the running tool call has written so far.
+	toolOutput string
+}
+
+// compactedMsg carries the summary produced for a turn that outgrew the
+// context window.
+type compactedMsg struct {
+	ctx     context.Context
+	summary llm.Message
+	covered int
+	err     error
 }
 
 func (c *ChatModel) Init() tea.Cmd {
@@ -35,27 +99,436 @@ func (c *ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 	log.Printf("ChatModel.Update called with msg type: %T", msg)
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
-	c.TextInput, cmd = c.TextInput.Update(msg)
+	c.Input, cmd = c.Input.Update(msg)
 	cmds = append(cmds, cmd)
 	c.Viewport, cmd = c.Viewport.Update(msg)
 	cmds = append(cmds, cmd)
 	c.Spinner, cmd = c.Spinner.Update(msg)
 	cmds = append(cmds, cmd)
-	c.List, cmd = c.List.Update(msg)
-	cmds = append(cmds, cmd)
+	// The list only mirrors the transcript; its key bindings, such as q to
+	// quit, must not see what is typed into the input.
+	if _, ok := msg.(tea.KeyMsg); !ok {
+		c.List, cmd = c.List.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 	return *c, tea.Batch(cmds...)
 }
 
-func (c *ChatModel) View() string {
-	log.Printf("ChatModel.View called: Width=%d, Height=%d", c.Width, c.Height)
+// appendMessage adds a message to the transcript and mirrors it in the list.
+func (c *ChatModel) appendMessage(msg llm.Message) {
+	c.Messages = append(c.Messages, msg)
+	c.List.InsertItem(len(c.List.Items()), Item(itemText(msg)))
+}
 
-	inputStyle := lipgloss.NewStyle()
-	if c.TextInput.Focused() {
-		inputStyle = inputStyle.Border(lipgloss.RoundedBorder(), true).BorderForeground(c.Theme.Accent1)
+// itemText is the list entry shown for a message.
+func itemText(msg llm.Message) string {
+	text := msg.Content
+	switch {
+	case msg.Role == "tool":
+		text = fmt.Sprintf("%s: %s", msg.ToolName, msg.Content)
+	case len(msg.ToolCalls) > 0:
+		for _, call := range msg.ToolCalls {
+			if text != "" {
+				text += "\n"
+			}
+			text += toolCallSummary(call)
+		}
 	}
+	if msg.Truncated {
+		text += " [generation stopped]"
+	}
+	return text
+}
 
-	chatContent := ""
+// Markdown renders the transcript as a Markdown document.
+func (c *ChatModel) Markdown() string {
+	var b strings.Builder
 	for _, msg := range c.Messages {
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&b, "### Tool: %s\n\n```\n%s\n```\n\n", msg.ToolName, msg.Content)
+			continue
+		case "assistant":
+			name := c.AssistantName
+			if name == "" {
+				name = "assistant"
+			}
+			fmt.Fprintf(&b, "## %s\n\n", name)
+		default:
+			fmt.Fprintf(&b, "## %s\n\n", msg.Role)
+		}
+		if msg.Content != "" {
+			b.WriteString(msg.Content + "\n\n")
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&b, "`%s`\n\n", toolCallSummary(call))
+		}
+		if msg.Truncated {
+			b.WriteString("*[generation stopped]*\n\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// toolCallSummary renders a tool call as a single line, e.g.
+// `→ calculator {"expression":"2+2"}`.
+func toolCallSummary(call llm.ToolCall) string {
+	if call.InvalidArguments != "" {
+		return fmt.Sprintf("→ %s %s", call.Name, call.InvalidArguments)
+	}
+	return fmt.Sprintf("→ %s %s", call.Name, string(call.Parameters))
+}
+
+// appendStreamChunk extends the assistant message currently being streamed,
+// starting a new one if the last message belongs to someone else.
+func (c *ChatModel) appendStreamChunk(chunk string) {
+	last := len(c.Messages) - 1
+	if last < 0 || c.Messages[last].Role != "assistant" || !c.Streaming {
+		c.appendMessage(llm.Message{Role: "assistant", Content: chunk})
+		return
+	}
+	c.Messages[last].Content += chunk
+	c.refreshLastItem()
+}
+
+// refreshLastItem re-renders the list entry for the last message.
+func (c *ChatModel) refreshLastItem() {
+	lastItemIndex := len(c.List.Items()) - 1
+	if lastItemIndex >= 0 && len(c.Messages) > 0 {
+		c.List.SetItem(lastItemIndex, Item(itemText(c.Messages[len(c.Messages)-1])))
+	}
+}
+
+// Send appends a user message and starts a new turn of the agent loop.
+func (c *ChatModel) Send(content string) tea.Cmd {
+	c.appendMessage(llm.Message{Role: "user", Content: content})
+	c.Viewport.GotoBottom()
+	c.saveSession()
+	c.toolIterations = 0
+	c.TurnUsage = llm.Usage{}
+	if c.ContextStrategy == llm.ContextSummarize && c.LlmClient.NeedsCompaction(c.contextMessages()) {
+		return c.startCompaction()
+	}
+	return c.StartStream()
+}
+
+// contextMessages is the conversation as sent to the model: the summary,
+// if any, followed by the messages it does not cover.
+func (c *ChatModel) contextMessages() []llm.Message {
+	if c.summarized == 0 {
+		return c.Messages
+	}
+	return append([]llm.Message{c.summary}, c.Messages[c.summarized:]...)
+}
+
+// ContextUsage estimates how full the model's context window is.
+func (c *ChatModel) ContextUsage() llm.ContextUsage {
+	return c.LlmClient.ContextUsage(c.contextMessages())
+}
+
+// startCompaction summarises the oldest turns in the background before the
+// turn's request is sent.
+func (c *ChatModel) startCompaction() tea.Cmd {
+	ctx := c.newContext()
+	c.compacting = ctx
+	c.Streaming = true
+	client, messages := c.LlmClient, c.contextMessages()
+	return func() tea.Msg {
+		summary, covered, err := client.Compact(ctx, messages)
+		return compactedMsg{ctx: ctx, summary: summary, covered: covered, err: err}
+	}
+}
+
+// handleCompacted adopts a finished summary and sends the turn. If the
+// summary failed, the turn is still sent and the client drops the oldest
+// turns instead.
+func (c *ChatModel) handleCompacted(msg compactedMsg) tea.Cmd {
+	if msg.ctx == nil || msg.ctx != c.compacting {
+		return nil
+	}
+	c.compacting = nil
+	if msg.err != nil {
+		log.Printf("context: %v", msg.err)
+		err := fmt.Errorf("%w; dropping the oldest messages instead", msg.err)
+		return tea.Batch(c.StartStream(), func() tea.Msg { return errorMsg{err} })
+	}
+	if msg.covered > 0 {
+		// covered counts the previous summary, which is not in Messages.
+		if c.summarized > 0 {
+			msg.covered--
+		}
+		c.summarized += msg.covered
+		c.summary = msg.summary
+	}
+	return c.StartStream()
+}
+
+// UseSessions enables saving to store. Messages already in the transcript,
+// such as the greeting, are treated as saved and never written.
+func (c *ChatModel) UseSessions(store *session.Store) {
+	c.Sessions = store
+	c.saved = len(c.Messages)
+	c.greeting = c.saved
+}
+
+// LoadSession replaces the transcript with a stored conversation; new
+// messages are appended to that session from then on.
+func (c *ChatModel) LoadSession(sess *session.Session) {
+	c.resetTranscript(sess.Messages)
+	c.Session = sess
+	c.saved = len(c.Messages)
+}
+
+// ImportSession replaces the transcript with a conversation read from a
+// file and saves it as a new session.
+func (c *ChatModel) ImportSession(sess *session.Session) {
+	c.resetTranscript(sess.Messages)
+	c.saveSession()
+}
+
+// Clear empties the transcript; the next message starts a new session.
+func (c *ChatModel) Clear() {
+	c.resetTranscript(nil)
+}
+
+// resetTranscript ends any turn in flight and starts over with messages,
+// detached from the current session.
+func (c *ChatModel) resetTranscript(messages []llm.Message) {
+	c.endStream()
+	c.Session = nil
+	c.saved, c.greeting = 0, 0
+	c.TurnUsage = llm.Usage{}
+	c.SessionUsage = nil
+	c.summary, c.summarized = llm.Message{}, 0
+	c.LlmClient.Permissions().ResetSession()
+	c.Messages = nil
+	c.List.SetItems(nil)
+	for _, msg := range messages {
+		c.appendMessage(msg)
+	}
+	c.Viewport.GotoTop()
+}
+
+// Retry drops everything after the last user message and asks the model
+// again.
+func (c *ChatModel) Retry() (tea.Cmd, error) {
+	last := len(c.Messages) - 1
+	for last >= 0 && c.Messages[last].Role != "user" {
+		last--
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("nothing to retry")
+	}
+	c.endStream()
+	keep := last + 1
+	if c.saved > keep && c.Session != nil {
+		if err := c.Sessions.Rewind(c.Session.ID, keep-c.greeting); err != nil {
+			log.Printf("session: %v", err)
+		}
+	}
+	c.saved = min(c.saved, keep)
+	c.Messages = c.Messages[:keep]
+	for len(c.List.Items()) > keep {
+		c.List.RemoveItem(len(c.List.Items()) - 1)
+	}
+	c.toolIterations = 0
+	c.TurnUsage = llm.Usage{}
+	return c.StartStream(), nil
+}
+
+// saveSession writes every finished message not yet on disk, creating the
+// session file on first use so idle launches leave nothing behind.
+func (c *ChatModel) saveSession() {
+	if c.Sessions == nil || c.saved >= len(c.Messages) {
+		return
+	}
+	if c.Session == nil {
+		sess, err := c.Sessions.Create(session.Info{
+			Model:        c.LlmClient.Model(),
+			Host:         c.LlmClient.Host(),
+			SystemPrompt: c.LlmClient.SystemPrompt(),
+		})
+		if err != nil {
+			log.Printf("session: %v", err)
+			return
+		}
+		c.Session = sess
+	}
+	if err := c.Sessions.Append(c.Session.ID, c.Messages[c.saved:]...); err != nil {
+		log.Printf("session: %v", err)
+		return
+	}
+	c.saved = len(c.Messages)
+}
+
+// saveSessionInfo records a change of model or system prompt in the
+// current session, so resuming it carries on with the new ones.
+func (c *ChatModel) saveSessionInfo() {
+	if c.Sessions == nil || c.Session == nil {
+		return
+	}
+	info := c.Session.Info
+	info.Model = c.LlmClient.Model()
+	info.Host = c.LlmClient.Host()
+	info.SystemPrompt = c.LlmClient.SystemPrompt()
+	if info == c.Session.Info {
+		return
+	}
+	if err := c.Sessions.UpdateInfo(c.Session.ID, info); err != nil {
+		log.Printf("session: %v", err)
+		return
+	}
+	c.Session.Info = info
+}
+
+// StartStream sends the transcript to the LLM and returns the command that
+// waits for the first event. Any stream already in flight is cancelled.
+func (c *ChatModel) StartStream() tea.Cmd {
+	ctx := c.newContext()
+	c.stream = c.LlmClient.SendMessageStream(ctx, c.contextMessages())
+	c.Streaming = true
+	return WaitForStreamEventCmd(c.stream)
+}
+
+// StopStreaming aborts the in-flight generation, keeping whatever partial
+// assistant reply has arrived and marking it as truncated.
+func (c *ChatModel) StopStreaming() {
+	if !c.Streaming {
+		return
+	}
+	last := len(c.Messages) - 1
+	if last >= 0 && c.Messages[last].Role == "assistant" && c.stream != nil {
+		c.Messages[last].Truncated = true
+		c.refreshLastItem()
+	}
+	c.endStream()
+}
+
+// endStream cancels whatever is in flight, answers any queued tool calls
+// as not run and clears Streaming.
+func (c *ChatModel) endStream() {
+	c.releaseContext()
+	c.stream = nil
+	c.toolCtx = nil
+	c.compacting = nil
+	c.answerPendingToolCalls("the user stopped it")
+	c.approving = false
+	c.preview = ""
+	c.toolOutput = ""
+	c.Streaming = false
+	c.saveSession()
+}
+
+// answerPendingToolCalls gives each queued call a "tool" message saying
+// why it was not run, since every tool call in the transcript must be
+// followed by its result.
+func (c *ChatModel) answerPendingToolCalls(reason string) {
+	for _, call := range c.pendingToolCalls {
+		c.appendMessage(llm.DeniedToolCall(call, reason))
+	}
+	c.pendingToolCalls = nil
+}
+
+// newContext cancels the previous step of the turn, if any, and returns the
+// context for the next one.
+func (c *ChatModel) newContext() context.Context {
+	c.releaseContext()
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	return ctx
+}
+
+func (c *ChatModel) releaseContext() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.cancel = nil
+}
+
+// finishRound handles the end of one model response. If the model asked
+// for tools it records the calls on the assistant message and returns the
+// command running the first one; otherwise the turn is over.
+func (c *ChatModel) finishRound(resp llm.Response) tea.Cmd {
+	c.stream = nil
+	c.recordUsage(resp.Metrics)
+	calls := resp.Message.ToolCalls
+	// This round's reply is the last message if any content was streamed;
+	// adopt the final, cleaned-up text the stream assembled.
+	last := len(c.Messages) - 1
+	replied := last >= 0 && c.Messages[last].Role == "assistant"
+	if replied {
+		c.Messages[last].Content = resp.Message.Content
+		c.refreshLastItem()
+	}
+	if len(calls) == 0 {
+		c.endStream()
+		return nil
+	}
+
+	// Ollama expects the assistant message carrying tool_calls to precede
+	// the tool results, so attach the calls to this round's reply.
+	if replied {
+		c.Messages[last].ToolCalls = calls
+		c.refreshLastItem()
+	} else {
+		c.appendMessage(llm.Message{Role: "assistant", ToolCalls: calls})
+	}
+
+	maxIterations := c.MaxToolIterations
+	if maxIterations <= 0 {
+		maxIterations = llm.DefaultMaxToolIterations
+	}
+	if c.toolIterations >= maxIterations {
+		c.pendingToolCalls = calls
+		c.answerPendingToolCalls("the tool iteration limit was reached")
+		c.endStream()
+		return func() tea.Msg { return errorMsg{llm.ErrToolIterationLimit} }
+	}
+	c.saveSession()
+	c.toolIterations++
+	c.pendingToolCalls = calls
+	c.toolCtx = c.newContext()
+	return c.runNextToolCall()
+}
+
+// recordUsage adds one response's metrics to the turn and session tallies.
+func (c *ChatModel) recordUsage(m llm.Metrics) {
+	c.TurnUsage.Add(m)
+	if c.SessionUsage == nil {
+		c.SessionUsage = make(map[string]llm.Usage)
+	}
+	model := c.LlmClient.Model()
+	u := c.SessionUsage[model]
+	u.Add(m)
+	c.SessionUsage[model] = u
+}
+
+// handleToolResult appends a finished tool call to the transcript, then runs
+// the next queued call or re-queries the model with the results. The result
+// of a call from a round that was stopped is dropped.
+func (c *ChatModel) handleToolResult(msg ToolResultMsg) tea.Cmd {
+	if !c.Streaming || len(c.pendingToolCalls) == 0 || msg.ctx != c.toolCtx {
+		return nil
+	}
+	c.toolOutput = ""
+	c.appendMessage(llm.Message{Role: "tool", Content: msg.Result, ToolName: msg.ToolName})
+	c.saveSession()
+	c.pendingToolCalls = c.pendingToolCalls[1:]
+	if len(c.pendingToolCalls) > 0 {
+		return c.runNextToolCall()
+	}
+	return c.StartStream()
+}
+
+// renderTranscript renders every message for the viewport. Assistant
+// replies are rendered as Markdown; the one still streaming is re-rendered
+// on each chunk and the rest come from the renderer's cache.
+func (c *ChatModel) renderTranscript() string {
+	if c.markdown == nil {
+		c.markdown = &markdownRenderer{}
+	}
+	chatContent := ""
+	for i, msg := range c.Messages {
 		var rendered string
 		switch msg.Role {
 		case "user":
@@ -65,50 +538,102 @@ func (c *ChatModel) View() string {
 			if name == "" {
 				name = "assistant"
 			}
-			rendered = c.Theme.AssistantMessage.Width(c.Width).Render(fmt.Sprintf("%s: %s", name, msg.Content))
+			rendered = c.Theme.AssistantMessage.Render(name)
+			if msg.Content != "" {
+				partial := c.Streaming && i == len(c.Messages)-1
+				rendered += "\n" + c.markdown.Render(c.Theme, c.Width, msg.Content, !partial)
+			}
+			if msg.Truncated {
+				rendered += "\n" + c.Theme.AssistantMessage.Italic(true).Render("[generation stopped]")
+			}
+			for _, call := range msg.ToolCalls {
+				rendered += "\n" + c.Theme.ToolMessage.Width(c.Width).Render(toolCallSummary(call))
+			}
 		case "tool":
-			rendered = c.Theme.ToolMessage.Width(c.Width).Render(fmt.Sprintf("tool: %s", msg.Content))
+			rendered = c.Theme.ToolMessage.Width(c.Width).Render(fmt.Sprintf("tool %s: %s", msg.ToolName, msg.Content))
 		default:
 			rendered = lipgloss.NewStyle().Width(c.Width).Render(fmt.Sprintf("%s: %s", msg.Role, msg.Content))
 		}
 		chatContent += rendered + "\n\n"
 	}
-	c.Viewport.SetContent(chatContent)
+	return chatContent
+}
+
+func (c *ChatModel) View() string {
+	log.Printf("ChatModel.View called: Width=%d, Height=%d", c.Width, c.Height)
+
+	inputStyle := lipgloss.NewStyle()
+	if c.Input.Focused() {
+		inputStyle = inputStyle.Border(lipgloss.RoundedBorder(), true).BorderForeground(c.Theme.Accent1)
+	}
 
 	spinnerView := ""
 	if c.Streaming {
-		spinnerView = c.Spinner.View() + " Generating..."
+		if c.compacting != nil {
+			spinnerView = c.Spinner.View() + " Summarizing earlier messages..."
+		} else if c.approving {
+			spinnerView = c.Spinner.View() + " Waiting for approval to run " + c.pendingToolCalls[0].Name + "..."
+		} else if len(c.pendingToolCalls) > 0 {
+			spinnerView = c.Spinner.View() + " Running " + c.pendingToolCalls[0].Name + "..."
+		} else {
+			spinnerView = c.Spinner.View() + " Generating..."
+		}
 	}
 
-	inputFieldRendered := inputStyle.Render(c.TextInput.View())
+	c.resizeInput(c.Width - inputStyle.GetHorizontalFrameSize())
+	inputFieldRendered := inputStyle.Render(c.Input.View())
+	if c.search != nil {
+		inputFieldRendered = c.searchView(c.Width)
+	} else if c.Input.Focused() {
+		inputFieldRendered = lipgloss.JoinVertical(lipgloss.Left, inputFieldRendered, c.inputStatus(c.Width))
+	}
 	log.Printf("ChatModel.View: inputFieldRendered height: %d", lipgloss.Height(inputFieldRendered))
 
 	tooltipHeight := 0
-	if !c.TextInput.Focused() {
+	if !c.Input.Focused() {
 		tooltip := lipgloss.NewStyle().Background(c.Theme.Primary2).Foreground(c.Theme.Accent2).Padding(0, 1).Render("Press Enter to send, Tab to switch panes, ? for help")
 		tooltipHeight = lipgloss.Height(tooltip)
 		inputFieldRendered = lipgloss.JoinVertical(lipgloss.Left, inputFieldRendered, tooltip)
 		log.Printf("ChatModel.View: tooltipHeight: %d, inputFieldRendered (with tooltip) height: %d", tooltipHeight, lipgloss.Height(inputFieldRendered))
 	}
 
-	// Calculate remaining height for the list
+	outputView := ""
+	if c.toolOutput != "" && len(c.pendingToolCalls) > 0 && !c.approving {
+		outputView = c.toolOutputView()
+	}
+
+	// Calculate remaining height for the transcript
 	remainingHeight := c.Height - lipgloss.Height(spinnerView) - lipgloss.Height(inputFieldRendered)
+	if outputView != "" {
+		remainingHeight -= lipgloss.Height(outputView)
+	}
 	if remainingHeight < 3 {
 		remainingHeight = 3
 	}
-	log.Printf("ChatModel.View: spinnerView height: %d, inputFieldRendered height: %d, remainingHeight for list: %d", lipgloss.Height(spinnerView), lipgloss.Height(inputFieldRendered), remainingHeight)
+	log.Printf("ChatModel.View: spinnerView height: %d, inputFieldRendered height: %d, remainingHeight for transcript: %d", lipgloss.Height(spinnerView), lipgloss.Height(inputFieldRendered), remainingHeight)
 
 	c.List.SetHeight(remainingHeight)
 	c.List.SetWidth(c.Width)
 	c.Viewport.Width = c.Width
 	c.Viewport.Height = remainingHeight
 
-	listView := c.List.View()
-	log.Printf("ChatModel.View: listView rendered height: %d", lipgloss.Height(listView))
+	// Keep following the conversation unless the user has scrolled up.
+	follow := c.Viewport.AtBottom()
+	c.Viewport.SetContent(c.renderTranscript())
+	if follow {
+		c.Viewport.GotoBottom()
+	}
+
+	transcriptView := c.Viewport.View()
+	log.Printf("ChatModel.View: transcriptView rendered height: %d", lipgloss.Height(transcriptView))
 	log.Printf("ChatModel.View: spinnerView rendered height: %d", lipgloss.Height(spinnerView))
 	log.Printf("ChatModel.View: inputFieldRendered rendered height: %d", lipgloss.Height(inputFieldRendered))
 
-	joined := lipgloss.JoinVertical(lipgloss.Left, listView, spinnerView, inputFieldRendered)
+	parts := []string{transcriptView, spinnerView, inputFieldRendered}
+	if outputView != "" {
+		parts = []string{transcriptView, outputView, spinnerView, inputFieldRendered}
+	}
+	joined := lipgloss.JoinVertical(lipgloss.Left, parts...)
 	log.Printf("ChatModel.View: joined rendered height: %d", lipgloss.Height(joined))
 	return joined
 }
@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"clai/internal/session"
+	"clai/internal/tools"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func newTestChat(t *testing.T, reply string) *ChatModel {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"message":{"role":"assistant","content":%q},"done":true}`+"\n", reply)
+	}))
+	t.Cleanup(srv.Close)
+	return &ChatModel{
+		LlmClient: llm.NewClient(srv.URL, "test", ""),
+		List:      list.New(nil, list.NewDefaultDelegate(), 0, 0),
+		Theme:     &DarkTheme,
+	}
+}
+
+func TestAgentLoopRunsToolsAndRequeries(t *testing.T) {
+	c := newTestChat(t, "done")
+	c.Send("say hi")
+	call := llm.ToolCall{Name: "echo", Parameters: json.RawMessage(`{"message":"hi"}`)}
+
+	cmd := c.finishRound(llm.Response{Message: llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{call}}})
+	if cmd == nil || !c.Streaming {
+		t.Fatal("expected a tool call to be scheduled")
+	}
+	result, ok := cmd().(ToolResultMsg)
+	if !ok || result.ToolName != "echo" || result.Result != "hi" {
+		t.Fatalf("unexpected tool result %+v", result)
+	}
+
+	next := c.handleToolResult(result)
+	if next == nil || c.stream == nil {
+		t.Fatal("expected the model to be re-queried after the last tool result")
+	}
+	ev := next().(StreamEventMsg)
+	if ev.Event.Type != llm.EventContent {
+		t.Fatalf("expected streamed content, got %s", ev.Event.Type)
+	}
+
+	roles := []string{}
+	for _, m := range c.Messages {
+		roles = append(roles, m.Role)
+	}
+	want := []string{"user", "assistant", "tool"}
+	if fmt.Sprint(roles) != fmt.Sprint(want) {
+		t.Errorf("roles = %v, want %v", roles, want)
+	}
+	c.StopStreaming()
+}
+
+func TestAgentLoopStopsAtIterationLimit(t *testing.T) {
+	c := newTestChat(t, "")
+	c.MaxToolIterations = 1
+	c.Send("loop")
+	call := llm.ToolCall{Name: "echo", Parameters: json.RawMessage(`{"message":"again"}`)}
+	resp := llm.Response{Message: llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{call}}}
+
+	cmd := c.finishRound(resp)
+	if cmd == nil {
+		t.Fatal("expected first round to run")
+	}
+	c.handleToolResult(cmd().(ToolResultMsg))
+	cmd = c.finishRound(resp)
+	if cmd == nil {
+		t.Fatal("expected an error command at the limit")
+	}
+	if msg, ok := cmd().(errorMsg); !ok || msg.err != llm.ErrToolIterationLimit {
+		t.Fatalf("expected iteration limit error, got %#v", msg)
+	}
+	if c.Streaming {
+		t.Error("streaming should be cleared once the limit is hit")
+	}
+	// The unanswered calls are answered, so the transcript can be sent on.
+	roles := []string{}
+	for _, m := range c.Messages {
+		roles = append(roles, m.Role)
+	}
+	if want := "[user assistant tool assistant tool]"; fmt.Sprint(roles) != want {
+		t.Fatalf("roles = %v, want %s", roles, want)
+	}
+	if last := c.Messages[len(c.Messages)-1]; last.ToolName != "echo" || !strings.Contains(last.Content, "iteration limit") {
+		t.Errorf("unexpected answer to the call at the limit: %+v", last)
+	}
+}
+
+func TestStoppingAnswersPendingToolCalls(t *testing.T) {
+	store := session.NewStore(t.TempDir())
+	c := newTestChat(t, "")
+	c.UseSessions(store)
+	c.Send("run it")
+	calls := []llm.ToolCall{
+		{Name: "echo", Parameters: json.RawMessage(`{"message":"one"}`)},
+		{Name: "echo", Parameters: json.RawMessage(`{"message":"two"}`)},
+	}
+	if cmd := c.finishRound(llm.Response{Message: llm.Message{Role: "assistant", ToolCalls: calls}}); cmd == nil {
+		t.Fatal("expected the calls to be scheduled")
+	}
+	c.StopStreaming()
+
+	loaded, err := store.Load(c.Session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msgs := range [][]llm.Message{c.Messages, loaded.Messages} {
+		if len(msgs) != 4 {
+			t.Fatalf("expected both calls answered, got %+v", msgs)
+		}
+		for _, m := range msgs[2:] {
+			if m.Role != "tool" || !strings.Contains(m.Content, "not run because the user stopped it") {
+				t.Errorf("unexpected answer %+v", m)
+			}
+		}
+	}
+}
+
+func TestLateToolResultOfStoppedTurnIsDropped(t *testing.T) {
+	c := newTestChat(t, "")
+	call := llm.ToolCall{Name: "echo", Parameters: json.RawMessage(`{"message":"old"}`)}
+	resp := llm.Response{Message: llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{call}}}
+	c.Send("first")
+	stale := c.finishRound(resp)().(ToolResultMsg)
+	c.StopStreaming()
+
+	c.Send("second")
+	if cmd := c.finishRound(resp); cmd == nil {
+		t.Fatal("expected the new call to be scheduled")
+	}
+	n := len(c.Messages)
+	if cmd := c.handleToolResult(stale); cmd != nil || len(c.Messages) != n || len(c.pendingToolCalls) != 1 {
+		t.Errorf("a result from the stopped turn answered the new call: %+v", c.Messages[n-1:])
+	}
+	c.StopStreaming()
+}
+
+func TestChatSavesAndLoadsSessions(t *testing.T) {
+	store := session.NewStore(t.TempDir())
+	c := newTestChat(t, "hello")
+	c.appendMessage(llm.Message{Role: "assistant", Content: "greeting"})
+	c.UseSessions(store)
+
+	c.Send("hi")
+	c.finishRound(llm.Response{Message: llm.Message{Role: "assistant", Content: "hello"}})
+	if c.Session == nil {
+		t.Fatal("expected a session to be created")
+	}
+
+	loaded, err := store.Load(c.Session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Messages) != 1 || loaded.Messages[0].Content != "hi" {
+		t.Fatalf("greeting should not be saved, got %+v", loaded.Messages)
+	}
+
+	other := newTestChat(t, "")
+	other.UseSessions(store)
+	other.LoadSession(loaded)
+	if len(other.Messages) != 1 || len(other.List.Items()) != 1 {
+		t.Errorf("session not restored: %+v", other.Messages)
+	}
+}
+
+func TestFinishRoundRecordsUsage(t *testing.T) {
+	c := newTestChat(t, "done")
+	metrics := llm.Metrics{PromptEvalCount: 10, EvalCount: 40, EvalDuration: 2 * time.Second}
+	for i := 0; i < 2; i++ {
+		c.Send("hi")
+		c.finishRound(llm.Response{Message: llm.Message{Role: "assistant", Content: "ok"}, Done: true, Metrics: metrics})
+	}
+	if c.TurnUsage.Responses != 1 || c.TurnUsage.CompletionTokens != 40 {
+		t.Errorf("turn usage should reset per message: %+v", c.TurnUsage)
+	}
+	if u := c.SessionUsage["test"]; u.Responses != 2 || u.String() != "20→80 tok, 20.0 tok/s" {
+		t.Errorf("session usage = %+v", u)
+	}
+
+	m := &Model{Chat: *c}
+	m.updateStatusBar()
+	if !strings.Contains(m.StatusBarText, "turn: 10→40 tok, 20.0 tok/s") || !strings.Contains(m.StatusBarText, "session: 20→80 tok") ||
+		!strings.Contains(m.StatusBarText, "ctx ▰") {
+		t.Errorf("status bar = %q", m.StatusBarText)
+	}
+}
+
+func TestSendSummarisesWhenContextIsFull(t *testing.T) {
+	c := newTestChat(t, "the story so far")
+	c.ContextStrategy = llm.ContextSummarize
+	// The tool definitions count against the window too; keep them out of
+	// the arithmetic.
+	c.LlmClient.SetRegistry(tools.NewRegistry())
+	c.LlmClient.SetContextLength(1000)
+	for i := 0; i < 4; i++ {
+		c.appendMessage(llm.Message{Role: "user", Content: strings.Repeat("q", 400)})
+		c.appendMessage(llm.Message{Role: "assistant", Content: strings.Repeat("a", 400)})
+	}
+
+	cmd := c.Send("next")
+	compacted, ok := cmd().(compactedMsg)
+	if !ok || !c.Streaming {
+		t.Fatalf("expected the turn to wait for a summary, got %#v", compacted)
+	}
+	if cmd := c.handleCompacted(compacted); cmd == nil || c.stream == nil {
+		t.Fatal("expected the message to be sent once summarised")
+	}
+	sent := c.contextMessages()
+	if c.summarized == 0 || sent[0].Content != llm.SummaryPrefix+"the story so far" || sent[1].Role != "user" {
+		t.Fatalf("summary not applied: summarized=%d, sent %+v", c.summarized, sent[:2])
+	}
+	if len(c.Messages) != 9 {
+		t.Errorf("the transcript should keep every message, has %d", len(c.Messages))
+	}
+	c.StopStreaming()
+
+	// A later summary also covers the earlier one, which is not a message.
+	before := c.summarized
+	c.compacting = context.Background()
+	c.handleCompacted(compactedMsg{ctx: c.compacting, summary: llm.Message{Role: "system", Content: "newer"}, covered: 3})
+	if c.summarized != before+2 || c.contextMessages()[0].Content != "newer" {
+		t.Errorf("summarized = %d, want %d", c.summarized, before+2)
+	}
+	c.StopStreaming()
+	if cmd := c.handleCompacted(compacted); cmd != nil {
+		t.Error("a stale summary should be ignored")
+	}
+}
@@ -0,0 +1,175 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Command is a slash command typed into the chat input, e.g. `/model llama3`.
+type Command struct {
+	Name string
+	// Args describes the arguments for the help listing, e.g. "[name]".
+	Args string
+	Help string
+	// Run carries out the command; args is everything after the name,
+	// trimmed.
+	Run func(m *Model, args string) tea.Cmd
+	// Complete, if set, returns the candidates for the argument being typed.
+	Complete func(m *Model, arg string) []string
+}
+
+// Commands holds the slash commands available in the chat input.
+type Commands struct {
+	byName map[string]Command
+}
+
+func NewCommands() *Commands {
+	return &Commands{byName: make(map[string]Command)}
+}
+
+// DefaultCommands holds the built-in commands; it is used by a Model whose
+// Commands field is nil.
+var DefaultCommands = NewCommands()
+
+// Register adds a command. Names must be unique and contain no spaces.
+func (c *Commands) Register(cmd Command) error {
+	if cmd.Name == "" || strings.ContainsAny(cmd.Name, " /") {
+		return fmt.Errorf("invalid command name %q", cmd.Name)
+	}
+	if cmd.Run == nil {
+		return fmt.Errorf("command /%s has no Run function", cmd.Name)
+	}
+	if _, exists := c.byName[cmd.Name]; exists {
+		return fmt.Errorf("command /%s already registered", cmd.Name)
+	}
+	c.byName[cmd.Name] = cmd
+	return nil
+}
+
+func (c *Commands) Get(name string) (Command, bool) {
+	cmd, ok := c.byName[name]
+	return cmd, ok
+}
+
+// Names returns the command names in sorted order.
+func (c *Commands) Names() []string {
+	names := make([]string, 0, len(c.byName))
+	for name := range c.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Help lists every command with its arguments and description.
+func (c *Commands) Help() string {
+	var b strings.Builder
+	for i, name := range c.Names() {
+		cmd := c.byName[name]
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		usage := "/" + name
+		if cmd.Args != "" {
+			usage += " " + cmd.Args
+		}
+		fmt.Fprintf(&b, "%-24s %s", usage, cmd.Help)
+	}
+	return b.String()
+}
+
+func (m *Model) commands() *Commands {
+	if m.Commands != nil {
+		return m.Commands
+	}
+	return DefaultCommands
+}
+
+// parseCommand splits input such as "/model llama3" into the command name
+// and its arguments. ok is false for ordinary messages, including ones
+// starting with "//", which are sent with the first slash removed.
+func parseCommand(input string) (name, args string, ok bool) {
+	rest, found := strings.CutPrefix(input, "/")
+	if !found || strings.HasPrefix(rest, "/") {
+		return "", "", false
+	}
+	name, args = rest, ""
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		name, args = rest[:i], rest[i:]
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// runCommand executes a slash command typed into the input.
+func (m *Model) runCommand(input string) tea.Cmd {
+	name, args, _ := parseCommand(input)
+	cmd, ok := m.commands().Get(name)
+	if !ok {
+		return func() tea.Msg {
+			return errorMsg{fmt.Errorf("unknown command /%s (type /help for a list, or start with // to send a message beginning with /)", name)}
+		}
+	}
+	return cmd.Run(m, args)
+}
+
+// completeInput completes the command name, or the argument, in a chat
+// input that starts with a slash. A single candidate is filled in; several
+// are narrowed to their common prefix and listed in a notice.
+func (m *Model) completeInput() tea.Cmd {
+	input := m.Chat.Input.Value()
+	name, args, ok := parseCommand(input)
+	if !ok {
+		return nil
+	}
+	var prefix string
+	var candidates []string
+	if !strings.Contains(input, " ") {
+		for _, n := range m.commands().Names() {
+			if strings.HasPrefix(n, name) {
+				candidates = append(candidates, n)
+			}
+		}
+		prefix = "/"
+	} else {
+		cmd, found := m.commands().Get(name)
+		if !found || cmd.Complete == nil {
+			return nil
+		}
+		for _, c := range cmd.Complete(m, args) {
+			if strings.HasPrefix(c, args) {
+				candidates = append(candidates, c)
+			}
+		}
+		prefix = "/" + name + " "
+	}
+	switch len(candidates) {
+	case 0:
+		return nil
+	case 1:
+		m.setInput(prefix + candidates[0] + " ")
+		return nil
+	default:
+		m.setInput(prefix + commonPrefix(candidates))
+		return notice(strings.Join(candidates, "  "))
+	}
+}
+
+// setInput replaces the chat input and moves the cursor to its end.
+func (m *Model) setInput(s string) {
+	m.Chat.Input.SetValue(s)
+	m.Chat.Input.CursorEnd()
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
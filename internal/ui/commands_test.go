@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"clai/internal/session"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newCommandModel(t *testing.T) *Model {
+	t.Helper()
+	m := &Model{Keys: DefaultKeyMap, Theme: DarkTheme, Chat: *newTestChat(t, "again")}
+	m.Chat.Input = NewInput(0)
+	m.Chat.Input.Focus()
+	return m
+}
+
+// enter types input into the chat box and presses enter.
+func enter(m *Model, input string) tea.Cmd {
+	m.Chat.Input.SetValue(input)
+	return m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestParseCommand(t *testing.T) {
+	for input, want := range map[string][2]string{
+		"/model":             {"model", ""},
+		"/model  llama3:8b ": {"model", "llama3:8b"},
+		"/system be brief":   {"system", "be brief"},
+	} {
+		name, args, ok := parseCommand(input)
+		if !ok || name != want[0] || args != want[1] {
+			t.Errorf("parseCommand(%q) = %q, %q, %v", input, name, args, ok)
+		}
+	}
+	for _, input := range []string{"hello", "//etc/hosts", ""} {
+		if _, _, ok := parseCommand(input); ok {
+			t.Errorf("%q parsed as a command", input)
+		}
+	}
+}
+
+func TestUnknownCommandShowsError(t *testing.T) {
+	m := newCommandModel(t)
+	msg, ok := enter(m, "/frobnicate now")().(errorMsg)
+	if !ok || !strings.Contains(msg.err.Error(), "/frobnicate") || !strings.Contains(msg.err.Error(), "//") {
+		t.Fatalf("expected an unknown command error, got %#v", msg)
+	}
+	if len(m.Chat.Messages) != 0 {
+		t.Error("commands must not be sent to the model")
+	}
+	if got := m.Chat.Input.Value(); got != "/frobnicate now" {
+		t.Errorf("unknown command cleared the input, left %q", got)
+	}
+
+	// A doubled slash sends the rest as a message.
+	enter(m, "//etc/hosts")
+	if len(m.Chat.Messages) != 1 || m.Chat.Messages[0].Content != "/etc/hosts" {
+		t.Errorf("unexpected messages %+v", m.Chat.Messages)
+	}
+	m.Chat.StopStreaming()
+}
+
+func TestTabCompletesCommands(t *testing.T) {
+	m := newCommandModel(t)
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+
+	m.Chat.Input.SetValue("/mo")
+	m.handleKeyMsg(tab)
+	if got := m.Chat.Input.Value(); got != "/model " {
+		t.Errorf("completed to %q", got)
+	}
+
+	m.Chat.Input.SetValue("/s")
+	cmd := m.handleKeyMsg(tab)
+	if n, ok := cmd().(noticeMsg); !ok || n.text != "save  set  system" {
+		t.Errorf("expected the candidates to be listed, got %#v", n)
+	}
+
+	m.Chat.Input.SetValue("/set temp")
+	m.handleKeyMsg(tab)
+	if got := m.Chat.Input.Value(); got != "/set temperature " {
+		t.Errorf("completed to %q", got)
+	}
+	if m.ActivePane != ChatPane {
+		t.Error("tab in a command should not switch panes")
+	}
+}
+
+func TestRetryRegeneratesLastReply(t *testing.T) {
+	m := newCommandModel(t)
+	store := session.NewStore(t.TempDir())
+	m.Chat.appendMessage(llm.Message{Role: "assistant", Content: "greeting"})
+	m.Chat.UseSessions(store)
+	m.Chat.Send("hi")
+	m.Chat.appendStreamChunk("first")
+	m.Chat.finishRound(llm.Response{Message: llm.Message{Role: "assistant", Content: "first"}})
+	if len(m.Chat.Messages) != 3 {
+		t.Fatalf("expected greeting, prompt and reply, got %+v", m.Chat.Messages)
+	}
+
+	if cmd := enter(m, "/retry"); cmd == nil || !m.Chat.Streaming {
+		t.Fatal("expected the model to be asked again")
+	}
+	if len(m.Chat.Messages) != 2 || len(m.Chat.List.Items()) != 2 || m.Chat.Messages[1].Content != "hi" {
+		t.Fatalf("reply not dropped: %+v", m.Chat.Messages)
+	}
+	m.Chat.appendStreamChunk("second")
+	m.Chat.finishRound(llm.Response{Message: llm.Message{Role: "assistant", Content: "second"}})
+	loaded, err := store.Load(m.Chat.Session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Messages) != 2 || loaded.Messages[1].Content != "second" {
+		t.Errorf("session not rewound: %+v", loaded.Messages)
+	}
+
+	enter(m, "/clear")
+	if len(m.Chat.Messages) != 0 || m.Chat.Session != nil {
+		t.Error("/clear should empty the transcript and detach the session")
+	}
+	if _, ok := enter(m, "/retry")().(errorMsg); !ok {
+		t.Error("expected an error retrying an empty conversation")
+	}
+}
+
+func TestSaveLoadAndExport(t *testing.T) {
+	dir := t.TempDir()
+	m := newCommandModel(t)
+	m.Chat.appendMessage(llm.Message{Role: "user", Content: "2+2?"})
+	m.Chat.appendMessage(llm.Message{Role: "assistant", Content: "**4**"})
+
+	file := filepath.Join(dir, "chat.jsonl")
+	if _, ok := enter(m, "/save "+file)().(noticeMsg); !ok {
+		t.Fatal("expected /save to report the file")
+	}
+	md := filepath.Join(dir, "chat.md")
+	enter(m, "/export "+md)
+	exported, err := os.ReadFile(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(exported) != "## user\n\n2+2?\n\n## assistant\n\n**4**\n" {
+		t.Errorf("unexpected export:\n%s", exported)
+	}
+
+	other := newCommandModel(t)
+	other.Chat.UseSessions(session.NewStore(dir))
+	if cmd := enter(other, "/load "+file); cmd != nil {
+		t.Fatalf("unexpected result %v", cmd())
+	}
+	if len(other.Chat.Messages) != 2 || other.Chat.Messages[1].Content != "**4**" || other.Chat.Session == nil {
+		t.Errorf("conversation not imported as a new session: %+v", other.Chat.Messages)
+	}
+}
+
+func TestModelAndSystemChangesAreSaved(t *testing.T) {
+	store := session.NewStore(t.TempDir())
+	m := newCommandModel(t)
+	m.Chat.UseSessions(store)
+	m.Chat.appendMessage(llm.Message{Role: "user", Content: "hi"})
+	m.Chat.saveSession()
+	if m.Chat.Session == nil {
+		t.Fatal("expected a session to be created")
+	}
+
+	enter(m, "/model qwen2.5")
+	enter(m, "/system be thorough")
+	loaded, err := store.Load(m.Chat.Session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Model != "qwen2.5" || loaded.SystemPrompt != "be thorough" || len(loaded.Messages) != 1 {
+		t.Errorf("changes not saved to the session: %+v", loaded)
+	}
+	if m.Chat.Session.Model != "qwen2.5" {
+		t.Errorf("in-memory session info not updated: %+v", m.Chat.Session.Info)
+	}
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg carries the text saved in the external editor.
+type editorFinishedMsg struct {
+	text string
+	err  error
+}
+
+// editorCommand is $VISUAL, else $EDITOR, else vi, split into the program
+// and its arguments so settings such as "code --wait" work.
+func editorCommand() (string, []string) {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields[0], fields[1:]
+		}
+	}
+	return "vi", nil
+}
+
+// openEditorCmd writes text to a temporary file and suspends the program
+// while the user edits it in their editor. The saved text comes back as an
+// editorFinishedMsg.
+func openEditorCmd(text string) tea.Cmd {
+	return editFileCmd(text, "clai-*.md", editorResult)
+}
+
+// editFileCmd opens text in the editor in a temporary file named after
+// pattern; result turns the file into the message delivered afterwards.
+func editFileCmd(text, pattern string, result func(path string, err error) tea.Msg) tea.Cmd {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return errorCmd(fmt.Errorf("creating file for the editor: %w", err))
+	}
+	path := f.Name()
+	_, err = f.WriteString(text)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return errorCmd(fmt.Errorf("writing file for the editor: %w", err))
+	}
+	return tea.ExecProcess(newEditorCmd(path), func(err error) tea.Msg {
+		return result(path, err)
+	})
+}
+
+func newEditorCmd(path string) *exec.Cmd {
+	name, args := editorCommand()
+	return exec.Command(name, append(args, path)...)
+}
+
+func editorResult(path string, runErr error) tea.Msg {
+	text, err := readEdited(path, runErr)
+	return editorFinishedMsg{text: text, err: err}
+}
+
+// readEdited reads back and removes the file once the editor has exited.
+func readEdited(path string, runErr error) (string, error) {
+	defer os.Remove(path)
+	if runErr != nil {
+		name, _ := editorCommand()
+		return "", fmt.Errorf("running %s: %w", name, runErr)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading edited file: %w", err)
+	}
+	// Editors add a final newline the text should not keep.
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// handleEditorFinished puts the edited text into the chat input.
+func (m *Model) handleEditorFinished(msg editorFinishedMsg) tea.Cmd {
+	if msg.err != nil {
+		return errorCmd(msg.err)
+	}
+	m.ActivePane = ChatPane
+	m.setInput(msg.text)
+	return m.Chat.Input.Focus()
+}
+
+// runEdit implements `/edit [n]`, opening the nth most recent message the
+// user sent, 1 being the last, in the editor.
+func runEdit(m *Model, arg string) tea.Cmd {
+	n := 1
+	if arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+			return errorCmd(fmt.Errorf("usage: /edit [n], where n counts back from your last message"))
+		}
+	}
+	for i := len(m.Chat.Messages) - 1; i >= 0; i-- {
+		if m.Chat.Messages[i].Role != "user" {
+			continue
+		}
+		if n--; n == 0 {
+			return openEditorCmd(m.Chat.Messages[i].Content)
+		}
+	}
+	return errorCmd(fmt.Errorf("there are not that many messages to edit"))
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorRoundTrip(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf ' and more\\n' >> \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+
+	path := filepath.Join(t.TempDir(), "draft.md")
+	if err := os.WriteFile(path, []byte("draft"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	msg := editorResult(path, newEditorCmd(path).Run()).(editorFinishedMsg)
+	if msg.err != nil || msg.text != "draft and more" {
+		t.Fatalf("unexpected result %+v", msg)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("temporary file was not removed")
+	}
+
+	m := newCommandModel(t)
+	m.ActivePane = LogPane
+	m.Update(msg)
+	if m.Chat.Input.Value() != "draft and more" || m.ActivePane != ChatPane {
+		t.Errorf("input %q, pane %v", m.Chat.Input.Value(), m.ActivePane)
+	}
+}
+
+func TestEditCommandPicksEarlierMessages(t *testing.T) {
+	// The draft file is left behind since the editor never runs.
+	t.Setenv("TMPDIR", t.TempDir())
+	m := newCommandModel(t)
+	if _, ok := enter(m, "/edit")().(errorMsg); !ok {
+		t.Error("expected an error with no messages")
+	}
+	m.Chat.appendMessage(llm.Message{Role: "user", Content: "first"})
+	m.Chat.appendMessage(llm.Message{Role: "assistant", Content: "reply"})
+	m.Chat.appendMessage(llm.Message{Role: "user", Content: "second"})
+	if _, ok := enter(m, "/edit 3")().(errorMsg); !ok {
+		t.Error("expected an error past the first message")
+	}
+	if _, ok := enter(m, "/edit x")().(errorMsg); !ok {
+		t.Error("expected a usage error")
+	}
+	if cmd := enter(m, "/edit 2"); cmd == nil {
+		t.Error("expected the editor to be opened")
+	}
+}
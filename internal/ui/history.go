@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"clai/internal/history"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// historySearch is the state of a ctrl+r reverse search through the
+// prompt history.
+type historySearch struct {
+	query string
+	// matches holds the prompts containing query, newest first, and index
+	// the one on show.
+	matches []string
+	index   int
+	prompts []string
+	// olderKey is the search key, shown as the way to older matches.
+	olderKey string
+}
+
+// historyPrompts returns the prompts that can be recalled, oldest first:
+// all of them, or with the session scope only those sent in the current
+// session.
+func (c *ChatModel) historyPrompts() []string {
+	if c.History == nil {
+		return nil
+	}
+	if c.HistoryScope != history.ScopeSession {
+		return c.History.Prompts("")
+	}
+	if c.Session == nil {
+		return nil
+	}
+	return c.History.Prompts(c.Session.ID)
+}
+
+// recordHistory adds text sent from the input to the history.
+func (c *ChatModel) recordHistory(text string) {
+	c.recall = nil
+	if c.History == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	id := ""
+	if c.Session != nil {
+		id = c.Session.ID
+	}
+	if err := c.History.Add(text, id); err != nil {
+		log.Printf("Failed to record history: %v", err)
+	}
+}
+
+// historyPrev replaces the input with the previous prompt, keeping the
+// draft so historyNext can return to it. It reports whether there was a
+// prompt to recall.
+func (c *ChatModel) historyPrev() bool {
+	if c.recall == nil {
+		prompts := c.historyPrompts()
+		if len(prompts) == 0 {
+			return false
+		}
+		c.recall = prompts
+		c.recallIndex = len(prompts)
+		c.draft = c.Input.Value()
+	}
+	if c.recallIndex > 0 {
+		c.recallIndex--
+	}
+	c.Input.SetValue(c.recall[c.recallIndex])
+	// Start on the first line so that pressing up again keeps going back
+	// through the history rather than through a multi-line prompt.
+	for c.Input.Line() > 0 {
+		c.Input.CursorUp()
+	}
+	c.Input.CursorStart()
+	return true
+}
+
+// historyNext moves forward through the history, back to the draft after
+// the newest prompt. It reports whether the input was being browsed.
+func (c *ChatModel) historyNext() bool {
+	if c.recall == nil {
+		return false
+	}
+	c.recallIndex++
+	if c.recallIndex >= len(c.recall) {
+		c.Input.SetValue(c.draft)
+		c.recall = nil
+	} else {
+		c.Input.SetValue(c.recall[c.recallIndex])
+	}
+	c.Input.CursorEnd()
+	return true
+}
+
+// startSearch opens the reverse search, showing the newest prompt.
+func (c *ChatModel) startSearch(olderKey string) {
+	c.search = &historySearch{prompts: c.historyPrompts(), olderKey: olderKey}
+	c.search.refresh()
+}
+
+// refresh finds the prompts matching the query, ignoring case.
+func (s *historySearch) refresh() {
+	s.matches = s.matches[:0]
+	s.index = 0
+	query := strings.ToLower(s.query)
+	for i := len(s.prompts) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(s.prompts[i]), query) {
+			s.matches = append(s.matches, s.prompts[i])
+		}
+	}
+}
+
+func (s *historySearch) match() (string, bool) {
+	if len(s.matches) == 0 {
+		return "", false
+	}
+	return s.matches[s.index], true
+}
+
+// handleSearchKey edits the search query. The search key again goes to the
+// next older match, enter or tab puts the match in the input and esc or
+// ctrl+c leaves the input as it was.
+func (m *Model) handleSearchKey(msg tea.KeyMsg) tea.Cmd {
+	s := m.Chat.search
+	switch {
+	case key.Matches(msg, m.Keys.HistorySearch):
+		if s.index < len(s.matches)-1 {
+			s.index++
+		}
+	case msg.Type == tea.KeyEnter || msg.Type == tea.KeyTab:
+		if text, ok := s.match(); ok {
+			m.setInput(text)
+		}
+		m.Chat.search = nil
+	case msg.Type == tea.KeyEsc || msg.Type == tea.KeyCtrlC:
+		m.Chat.search = nil
+	case msg.Type == tea.KeyBackspace:
+		if r := []rune(s.query); len(r) > 0 {
+			s.query = string(r[:len(r)-1])
+			s.refresh()
+		}
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+		s.query += string(msg.Runes)
+		s.refresh()
+	}
+	return nil
+}
+
+// searchView draws the reverse search in place of the input: the query,
+// the match on show, and the keys.
+func (c *ChatModel) searchView(width int) string {
+	s := c.search
+	label := "reverse search: "
+	text, ok := s.match()
+	if !ok && s.query != "" {
+		label = "failing reverse search: "
+	}
+	maxHeight := c.InputMaxHeight
+	if maxHeight <= 0 {
+		maxHeight = DefaultInputMaxHeight
+	}
+	box := lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).BorderForeground(c.Theme.Accent1)
+	inner := max(width-box.GetHorizontalFrameSize(), 1)
+	accent := lipgloss.NewStyle().Foreground(c.Theme.Accent1)
+	query := accent.Render(label) + s.query + "▏"
+	match := lipgloss.NewStyle().Width(inner).MaxHeight(maxHeight).Render(text)
+	position := ""
+	if ok {
+		position = fmt.Sprintf("%d/%d", s.index+1, len(s.matches))
+	}
+	status := lipgloss.NewStyle().Foreground(c.Theme.Accent2).Faint(true)
+	hint := s.olderKey + " older · enter use · esc cancel"
+	gap := max(width-lipgloss.Width(hint)-lipgloss.Width(position), 1)
+	return lipgloss.JoinVertical(lipgloss.Left,
+		box.Render(lipgloss.JoinVertical(lipgloss.Left, query, match)),
+		status.Render(hint+strings.Repeat(" ", gap)+position),
+	)
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"clai/internal/history"
+	"clai/internal/session"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newHistoryModel(t *testing.T) *Model {
+	t.Helper()
+	m := newCommandModel(t)
+	h, err := history.Load("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Chat.History = h
+	return m
+}
+
+func press(m *Model, k tea.KeyType) {
+	m.handleKeyMsg(tea.KeyMsg{Type: k})
+}
+
+func TestUpDownRecallsHistory(t *testing.T) {
+	m := newHistoryModel(t)
+	for _, prompt := range []string{"first", "two\nlines", "first"} {
+		enter(m, prompt)
+		m.Chat.StopStreaming()
+	}
+	typeRunes(m, "draft")
+
+	var got []string
+	for range 3 {
+		press(m, tea.KeyUp)
+		got = append(got, m.Chat.Input.Value())
+	}
+	// Repeats are recalled once, and up keeps going back from the first
+	// line of a multi-line prompt.
+	if len(got) != 3 || got[0] != "first" || got[1] != "two\nlines" || got[2] != "two\nlines" {
+		t.Fatalf("recalled %q", got)
+	}
+	press(m, tea.KeyDown) // moves to the second line
+	press(m, tea.KeyDown)
+	if v := m.Chat.Input.Value(); v != "first" {
+		t.Errorf("down recalled %q", v)
+	}
+	press(m, tea.KeyDown)
+	if v := m.Chat.Input.Value(); v != "draft" {
+		t.Errorf("down past the newest prompt should restore the draft, got %q", v)
+	}
+}
+
+func TestReverseSearch(t *testing.T) {
+	m := newHistoryModel(t)
+	for _, prompt := range []string{"explain goroutines", "/model llama3", "explain channels"} {
+		m.Chat.recordHistory(prompt)
+	}
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyCtrlR})
+	typeRunes(m, "EXPL")
+	if text, _ := m.Chat.search.match(); text != "explain channels" {
+		t.Fatalf("match = %q", text)
+	}
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyCtrlR})
+	press(m, tea.KeyEnter)
+	if m.Chat.search != nil || m.Chat.Input.Value() != "explain goroutines" || len(m.Chat.Messages) != 0 {
+		t.Errorf("enter should put the match in the input without sending, input %q", m.Chat.Input.Value())
+	}
+
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyCtrlR})
+	typeRunes(m, "zzz")
+	m.Chat.Width, m.Chat.Height = 60, 30
+	if view := m.Chat.View(); !strings.Contains(view, "failing reverse search: zzz") {
+		t.Errorf("search not drawn:\n%s", view)
+	}
+	press(m, tea.KeyEsc)
+	if m.Chat.search != nil || m.Chat.Input.Value() != "explain goroutines" {
+		t.Errorf("esc should leave the input alone, got %q", m.Chat.Input.Value())
+	}
+}
+
+func TestSessionScopedHistory(t *testing.T) {
+	m := newHistoryModel(t)
+	m.Chat.HistoryScope = history.ScopeSession
+	m.Chat.History.Add("elsewhere", "other")
+	m.Chat.Session = &session.Session{Info: session.Info{ID: "mine"}}
+	m.Chat.recordHistory("here")
+	press(m, tea.KeyUp)
+	press(m, tea.KeyUp)
+	if v := m.Chat.Input.Value(); v != "here" {
+		t.Errorf("recalled %q from another session", v)
+	}
+}
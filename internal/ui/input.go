@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultInputMaxHeight is how many rows the chat input grows to before it
+// scrolls.
+const DefaultInputMaxHeight = 8
+
+const inputPrompt = "> "
+
+// NewInput returns the chat input: a multi-line editor that starts one row
+// high and grows with its content. Enter is handled by Model, which sends
+// the message, so newlines are inserted with alt+enter (which is what most
+// terminals send for shift+enter) or ctrl+j. charLimit of 0 means no limit.
+func NewInput(charLimit int) textarea.Model {
+	input := textarea.New()
+	input.Placeholder = "Type your message..."
+	input.CharLimit = charLimit
+	// The number of lines is unlimited; ChatModel sets the visible height.
+	input.MaxHeight = 0
+	input.ShowLineNumbers = false
+	input.SetPromptFunc(len(inputPrompt), func(line int) string {
+		if line == 0 {
+			return inputPrompt
+		}
+		return strings.Repeat(" ", len(inputPrompt))
+	})
+	input.KeyMap.InsertNewline = key.NewBinding(
+		key.WithKeys("alt+enter", "ctrl+j"),
+		key.WithHelp("alt+enter", "newline"),
+	)
+	input.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	input.SetHeight(1)
+	return input
+}
+
+// resizeInput fits the input to width and grows it to show every wrapped
+// line of its content, up to InputMaxHeight rows.
+func (c *ChatModel) resizeInput(width int) {
+	c.Input.SetWidth(width)
+	maxHeight := c.InputMaxHeight
+	if maxHeight <= 0 {
+		maxHeight = DefaultInputMaxHeight
+	}
+	rows := 0
+	textWidth := max(c.Input.Width(), 1)
+	for _, line := range strings.Split(c.Input.Value(), "\n") {
+		rows += runewidth.StringWidth(line)/textWidth + 1
+	}
+	c.Input.SetHeight(min(rows, maxHeight))
+}
+
+// inputStatus is the line under the input: a reminder of the keys, and the
+// size of the draft once there is one.
+func (c *ChatModel) inputStatus(width int) string {
+	style := lipgloss.NewStyle().Foreground(c.Theme.Accent2).Faint(true)
+	hint := "enter send · alt+enter newline"
+	count := ""
+	if value := c.Input.Value(); value != "" {
+		count = fmt.Sprintf("%d chars · ~%d tok", c.Input.Length(), llm.EstimateTokens(value))
+		if limit := c.Input.CharLimit; limit > 0 {
+			count = fmt.Sprintf("%d/%d chars · ~%d tok", c.Input.Length(), limit, llm.EstimateTokens(value))
+		}
+	}
+	gap := width - lipgloss.Width(hint) - lipgloss.Width(count)
+	if gap < 1 {
+		return style.Render(count)
+	}
+	return style.Render(hint + strings.Repeat(" ", gap) + count)
+}
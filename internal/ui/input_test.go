@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeRunes(m *Model, s string) tea.Cmd {
+	return m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+}
+
+func TestInputSendsMultilineMessages(t *testing.T) {
+	m := newCommandModel(t)
+	m.Chat.Width, m.Chat.Height = 60, 30
+
+	// Letters bound to shortcuts are text while typing.
+	if cmd := typeRunes(m, "quit"); cmd != nil {
+		if _, quit := cmd().(tea.QuitMsg); quit {
+			t.Fatal("typing q quit the program")
+		}
+	}
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+	typeRunes(m, "then")
+	// A paste arrives as one message and keeps its newlines.
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("\nfunc main() {\n}"), Paste: true})
+	want := "quit\nthen\nfunc main() {\n}"
+	if got := m.Chat.Input.Value(); got != want {
+		t.Fatalf("input = %q, want %q", got, want)
+	}
+	if m.Theme.Name != DarkTheme.Name || m.ShowHelp {
+		t.Error("typed letters triggered shortcuts")
+	}
+
+	view := m.Chat.View()
+	if m.Chat.Input.Height() != 4 || !strings.Contains(view, "25 chars · ~7 tok") {
+		t.Errorf("input height %d, view:\n%s", m.Chat.Input.Height(), view)
+	}
+
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.Chat.Messages) != 1 || m.Chat.Messages[0].Content != want || m.Chat.Input.Value() != "" {
+		t.Fatalf("message not sent: %+v", m.Chat.Messages)
+	}
+	m.Chat.StopStreaming()
+	m.Chat.View()
+	if m.Chat.Input.Height() != 1 {
+		t.Errorf("input should shrink back to one row, is %d", m.Chat.Input.Height())
+	}
+}
+
+func TestInputGrowsUpToMaxHeight(t *testing.T) {
+	c := newTestChat(t, "")
+	c.Input = NewInput(0)
+	c.InputMaxHeight = 3
+	c.Input.SetValue(strings.Repeat("word ", 100))
+	c.resizeInput(40)
+	if c.Input.Height() != 3 {
+		t.Errorf("height = %d, want the maximum of 3", c.Input.Height())
+	}
+}
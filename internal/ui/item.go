@@ -1,5 +1,12 @@
 package ui
 
+import (
+	"clai/internal/llm"
+	"clai/internal/session"
+	"fmt"
+	"strings"
+)
+
 type Item string
 
 func (i Item) FilterValue() string { return "" }
@@ -7,3 +14,61 @@ func (i Item) FilterValue() string { return "" }
 func (i Item) Title() string { return string(i) }
 
 func (i Item) Description() string { return "" }
+
+// sessionItem is a stored session in the session browser.
+type sessionItem struct{ session.Summary }
+
+func (i sessionItem) FilterValue() string { return i.Title() + " " + i.Model }
+
+func (i sessionItem) Title() string {
+	if i.Summary.Title == "" {
+		return "(empty session)"
+	}
+	return i.Summary.Title
+}
+
+func (i sessionItem) Description() string {
+	return fmt.Sprintf("%s · %d messages · %s", i.Model, i.MessageCount, i.UpdatedAt.Format("2006-01-02 15:04"))
+}
+
+// modelItem is an installed model in the model picker.
+type modelItem struct {
+	llm.ModelInfo
+	current bool
+}
+
+func (i modelItem) FilterValue() string { return i.Name }
+
+func (i modelItem) Title() string {
+	if i.current {
+		return i.Name + " (current)"
+	}
+	return i.Name
+}
+
+func (i modelItem) Description() string {
+	var parts []string
+	for _, p := range []string{i.ParameterSize, i.Quantization} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if i.Size > 0 {
+		parts = append(parts, formatBytes(i.Size))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatBytes renders n in decimal units, e.g. 4.7 GB.
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -1,21 +1,31 @@
 package ui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
 
 type KeyMap struct {
-	Quit key.Binding
-	Help key.Binding
-	Tab  key.Binding
-	ToggleTheme key.Binding
+	Quit          key.Binding
+	Help          key.Binding
+	Tab           key.Binding
+	ToggleTheme   key.Binding
+	Stop          key.Binding
+	Sessions      key.Binding
+	Models        key.Binding
+	Editor        key.Binding
+	HistorySearch key.Binding
 }
 
 func (k KeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Help, k.Quit, k.ToggleTheme}
+	return []key.Binding{k.Help, k.Quit, k.Stop, k.ToggleTheme}
 }
 
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Help, k.Quit, k.Tab, k.ToggleTheme},
+		{k.Help, k.Quit, k.Tab, k.ToggleTheme, k.Stop, k.Sessions, k.Models, k.Editor, k.HistorySearch},
 	}
 }
 
@@ -36,4 +46,59 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("t"),
 		key.WithHelp("t", "toggle theme"),
 	),
-}
\ No newline at end of file
+	Stop: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "stop generation"),
+	),
+	Sessions: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "browse sessions"),
+	),
+	Models: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "switch model"),
+	),
+	Editor: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("ctrl+g", "edit in $EDITOR"),
+	),
+	HistorySearch: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "search history"),
+	),
+}
+
+// bindings maps the action names used in the config file to their bindings.
+func (k *KeyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":           &k.Quit,
+		"help":           &k.Help,
+		"tab":            &k.Tab,
+		"toggle_theme":   &k.ToggleTheme,
+		"stop":           &k.Stop,
+		"sessions":       &k.Sessions,
+		"models":         &k.Models,
+		"editor":         &k.Editor,
+		"history_search": &k.HistorySearch,
+	}
+}
+
+// Apply rebinds actions to the keys given in the config file, keeping each
+// action's help description.
+func (k *KeyMap) Apply(overrides map[string][]string) error {
+	bindings := k.bindings()
+	for action, keys := range overrides {
+		b, ok := bindings[action]
+		if !ok {
+			return fmt.Errorf("unknown key action %q", action)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys given for action %q", action)
+		}
+		*b = key.NewBinding(
+			key.WithKeys(keys...),
+			key.WithHelp(strings.Join(keys, "/"), b.Help().Desc),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package ui
+
+import "testing"
+
+func TestKeyMapApply(t *testing.T) {
+	keys := DefaultKeyMap
+	if err := keys.Apply(map[string][]string{"stop": {"ctrl+x", "f2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys.Stop.Keys(); len(got) != 2 || got[0] != "ctrl+x" {
+		t.Errorf("stop keys = %v", got)
+	}
+	if keys.Stop.Help().Key != "ctrl+x/f2" || keys.Stop.Help().Desc != "stop generation" {
+		t.Errorf("stop help = %+v", keys.Stop.Help())
+	}
+	if DefaultKeyMap.Stop.Keys()[0] != "esc" {
+		t.Error("Apply must not modify DefaultKeyMap")
+	}
+	if err := keys.Apply(map[string][]string{"launch": {"x"}}); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
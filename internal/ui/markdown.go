@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/glamour/styles"
+)
+
+// markdownRenderer renders assistant replies as terminal Markdown in the
+// colors of the active Theme. Building a glamour renderer is expensive, so
+// one is kept until the theme or width changes, and finished messages are
+// cached so only the reply that is still streaming is re-rendered.
+type markdownRenderer struct {
+	theme    string
+	width    int
+	renderer *glamour.TermRenderer
+	cache    map[string]string
+}
+
+// Render returns content rendered for theme at width. Set cache for text
+// that will not change again; partial replies are rendered every time.
+// If rendering fails the content is returned as is.
+func (m *markdownRenderer) Render(theme *Theme, width int, content string, cache bool) string {
+	if m.renderer == nil || m.theme != theme.Name || m.width != width {
+		r, err := glamour.NewTermRenderer(
+			glamour.WithStyles(markdownStyle(theme)),
+			glamour.WithWordWrap(width),
+		)
+		if err != nil {
+			log.Printf("markdown: %v", err)
+			return content
+		}
+		m.renderer, m.theme, m.width = r, theme.Name, width
+		m.cache = make(map[string]string)
+	}
+	if out, ok := m.cache[content]; ok {
+		return out
+	}
+	out, err := m.renderer.Render(closeFences(content))
+	if err != nil {
+		log.Printf("markdown: %v", err)
+		return content
+	}
+	out = strings.Trim(out, "\n")
+	if cache {
+		m.cache[content] = out
+	}
+	return out
+}
+
+// closeFences terminates a fenced code block left open at the end of s, as
+// happens midway through a streamed reply. Without it the code would be
+// rendered as a paragraph until the closing fence arrived and then jump
+// into a highlighted block.
+func closeFences(s string) string {
+	open := ""
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) > 3 {
+			continue
+		}
+		fence := fenceMarker(trimmed)
+		switch {
+		case fence == "":
+		case open == "":
+			open = fence
+		case fence[0] == open[0] && len(fence) >= len(open) && strings.TrimSpace(trimmed[len(fence):]) == "":
+			open = ""
+		}
+	}
+	if open == "" {
+		return s
+	}
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s + open
+}
+
+// fenceMarker returns the run of backticks or tildes opening line if it is
+// long enough to be a code fence.
+func fenceMarker(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := len(line) - len(strings.TrimLeft(line, line[:1]))
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
+
+// markdownStyle derives a glamour style from theme, starting from glamour's
+// own dark or light style and recoloring it with the theme palette.
+func markdownStyle(theme *Theme) ansi.StyleConfig {
+	cfg := styles.DarkStyleConfig
+	if theme.Name == LightTheme.Name {
+		cfg = styles.LightStyleConfig
+	}
+	color := func(c string) *string { return &c }
+	primary1 := color(string(theme.Primary1))
+	primary2 := color(string(theme.Primary2))
+	primary3 := color(string(theme.Primary3))
+	accent1 := color(string(theme.Accent1))
+	accent2 := color(string(theme.Accent2))
+	border := color(string(theme.BorderCol))
+	bgLight := color(string(theme.BgLight))
+
+	// The chat pane supplies its own padding.
+	cfg.Document.BlockPrefix = ""
+	cfg.Document.BlockSuffix = ""
+	cfg.Document.Margin = nil
+	cfg.Document.Color = accent2
+
+	cfg.Heading.Color = accent1
+	cfg.H1.Color = accent2
+	cfg.H1.BackgroundColor = primary1
+	cfg.H6.Color = primary3
+	cfg.HorizontalRule.Color = border
+	cfg.BlockQuote.Color = primary3
+	cfg.Link.Color = primary3
+	cfg.LinkText.Color = accent1
+	cfg.Image.Color = primary3
+	cfg.ImageText.Color = primary2
+	cfg.Code.Color = accent1
+	cfg.Code.BackgroundColor = bgLight
+	cfg.CodeBlock.Color = primary3
+	cfg.Table.Color = accent2
+
+	chroma := *cfg.CodeBlock.Chroma
+	chroma.Text.Color = accent2
+	chroma.Name.Color = accent2
+	chroma.Comment.Color = border
+	chroma.Keyword.Color = accent1
+	chroma.KeywordType.Color = primary3
+	chroma.NameFunction.Color = primary3
+	chroma.LiteralString.Color = primary2
+	chroma.Background.BackgroundColor = bgLight
+	cfg.CodeBlock.Chroma = &chroma
+	return cfg
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestCloseFences(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"```go\nfmt.Println()", "```go\nfmt.Println()\n```"},
+		{"```go\nx := 1\n```\nafter", "```go\nx := 1\n```\nafter"},
+		{"~~~~\ncode\n~~~", "~~~~\ncode\n~~~\n~~~~"},
+		{"````\n```\nnested", "````\n```\nnested\n````"},
+		{"```\n", "```\n```"},
+		{"use `` inline", "use `` inline"},
+	}
+	for _, tt := range tests {
+		if got := closeFences(tt.in); got != tt.want {
+			t.Errorf("closeFences(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	theme := DarkTheme
+	theme.ApplyStyles()
+	var r markdownRenderer
+
+	out := ansi.Strip(r.Render(&theme, 40, "# Title\n\n- one\n- two\n\n```go\nfunc main() {", false))
+	for _, want := range []string{"Title", "• one", "func main() {"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "```") {
+		t.Errorf("unterminated fence rendered literally:\n%s", out)
+	}
+	if len(r.cache) != 0 {
+		t.Error("partial content should not be cached")
+	}
+
+	r.Render(&theme, 40, "done", true)
+	if _, ok := r.cache["done"]; !ok {
+		t.Error("final content should be cached")
+	}
+	light := LightTheme
+	r.Render(&light, 40, "done", true)
+	if r.theme != light.Name || len(r.cache) != 1 {
+		t.Error("switching theme should rebuild the renderer and reset the cache")
+	}
+}
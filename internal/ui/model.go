@@ -3,12 +3,16 @@ package ui
 import (
 	"bufio"
 	"clai/internal/llm"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -43,47 +47,76 @@ type Model struct {
 	ErrorMessage  string
 	ShowError     bool
 	Theme         Theme
+	SessionList   list.Model
+	ShowSessions  bool
+	ModelList     list.Model
+	ShowModels    bool
+	pull          *pullState
+	// Commands are the slash commands; nil means DefaultCommands.
+	Commands *Commands
+	// Notice is informational text, such as command output, shown below
+	// the status bar until it times out.
+	Notice     string
+	noticeSeq  int
+	modelNames []string
+	// LogFile is tailed into the log pane; empty leaves the pane blank.
+	LogFile string
+}
+
+// ToolResultMsg is the outcome of a tool call. ctx identifies the round the
+// call belongs to, so the result of a call that was stopped is dropped.
+type ToolResultMsg struct {
+	ToolName, Result string
+	ctx              context.Context
 }
 
 type (
-	ToolResultMsg      struct{ ToolName, Result string }
 	LogUpdateMsg       string
 	LLMResponseMsg     struct{ Resp llm.Response }
-	StreamUpdateMsg    string
 	TickMsg            struct{}
 	HealthCheckMsg     struct{ Err error }
 	HealthCheckDoneMsg struct{}
 	errorMsg           struct{ err error }
 	clearErrorMsg      struct{}
+	noticeMsg          struct{ text string }
+	clearNoticeMsg     struct{ seq int }
 )
 
-func StreamLLMResponseCmd(llmClient *llm.Client, messages []llm.Message) tea.Cmd {
+// noticeDuration is how long a notice stays on screen.
+const noticeDuration = 10 * time.Second
+
+func notice(text string) tea.Cmd {
+	return func() tea.Msg { return noticeMsg{text} }
+}
+
+// StreamEventMsg delivers one event from an in-flight LLM stream along with
+// the channel to keep reading from.
+type StreamEventMsg struct {
+	Event  llm.StreamEvent
+	events <-chan llm.StreamEvent
+}
+
+// WaitForStreamEventCmd blocks on the next event of an in-flight stream.
+func WaitForStreamEventCmd(events <-chan llm.StreamEvent) tea.Cmd {
 	return func() tea.Msg {
-		streamChan := make(chan string)
-		go func() {
-			_, err := llmClient.SendMessageStream(messages, streamChan)
-			if err != nil {
-				streamChan <- "[LLM ERROR] " + err.Error()
-				close(streamChan)
-			}
-		}()
-		for chunk := range streamChan {
-			return StreamUpdateMsg(chunk)
+		ev, ok := <-events
+		if !ok {
+			return StreamEventMsg{Event: llm.StreamEvent{Type: llm.EventDone}}
 		}
-		return nil
+		return StreamEventMsg{Event: ev, events: events}
 	}
 }
 
-func TailLogFileCmd() tea.Cmd {
+func TailLogFileCmd(path string) tea.Cmd {
 	return func() tea.Msg {
 		logChan := make(chan tea.Msg)
-		go tailLogFile(logChan)
+		go tailLogFile(path, logChan)
 		return readLogChanCmd(logChan)
 	}
 }
 
-func tailLogFile(logChan chan<- tea.Msg) {
-	f, err := os.Open("debug.log")
+func tailLogFile(path string, logChan chan<- tea.Msg) {
+	f, err := os.Open(path)
 	if err == nil {
 		scanner := bufio.NewScanner(f)
 		for scanner.Scan() {
@@ -91,13 +124,13 @@ func tailLogFile(logChan chan<- tea.Msg) {
 		}
 		f.Close()
 	}
-	t, err := os.Stat("debug.log")
+	t, err := os.Stat(path)
 	var offset int64 = 0
 	if err == nil {
 		offset = t.Size()
 	}
 	for {
-		file, err := os.Open("debug.log")
+		file, err := os.Open(path)
 		if err != nil {
 			time.Sleep(1 * time.Second)
 			continue
@@ -118,12 +151,11 @@ func readLogChanCmd(logChan chan tea.Msg) tea.Cmd {
 	return func() tea.Msg { return <-logChan }
 }
 
-func StartsWithLLMError(s string) bool {
-	return len(s) >= 11 && s[:11] == "[LLM ERROR]"
-}
-
 func (m *Model) Init() tea.Cmd {
-	return tea.Batch(TailLogFileCmd(), m.Chat.Init())
+	if m.LogFile == "" {
+		return m.Chat.Init()
+	}
+	return tea.Batch(TailLogFileCmd(m.LogFile), m.Chat.Init())
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -134,27 +166,33 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, m.handleWindowSizeMsg(msg))
 	case tea.KeyMsg:
 		cmds = append(cmds, m.handleKeyMsg(msg))
-	case StreamUpdateMsg:
-		chunk := string(msg)
-		if len(chunk) == 0 {
-			m.Chat.Streaming = false
-			break
-		}
-		if len(m.Chat.Messages) > 0 && m.Chat.Messages[len(m.Chat.Messages)-1].Role == "assistant" && m.Chat.Streaming && !StartsWithLLMError(chunk) {
-			m.Chat.Messages[len(m.Chat.Messages)-1].Content += chunk
-			// Update the last item in the list
-			lastItemIndex := len(m.Chat.List.Items()) - 1
-			if lastItemIndex >= 0 {
-				lastItem := m.Chat.List.Items()[lastItemIndex].(Item)
-				m.Chat.List.SetItem(lastItemIndex, Item(string(lastItem)+chunk))
-			}
-		} else {
-			m.Chat.Messages = append(m.Chat.Messages, llm.Message{Role: "assistant", Content: chunk})
-			m.Chat.List.InsertItem(len(m.Chat.List.Items()), Item(chunk))
-		}
-		if StartsWithLLMError(chunk) {
-			m.Chat.Streaming = false
+	case StreamEventMsg:
+		cmds = append(cmds, m.handleStreamEvent(msg))
+	case ToolResultMsg:
+		cmds = append(cmds, m.Chat.handleToolResult(msg))
+	case toolOutputMsg:
+		cmds = append(cmds, m.Chat.handleToolOutput(msg))
+	case editorFinishedMsg:
+		cmds = append(cmds, m.handleEditorFinished(msg))
+	case argsEditedMsg:
+		cmds = append(cmds, m.handleArgsEdited(msg))
+	case compactedMsg:
+		cmds = append(cmds, m.Chat.handleCompacted(msg))
+		m.updateStatusBar()
+	case modelsMsg:
+		cmds = append(cmds, m.openModelPicker(msg))
+	case PullEventMsg:
+		cmds = append(cmds, m.handlePullEvent(msg))
+	case list.FilterMatchesMsg:
+		// Filter results belong to whichever modal list is being filtered.
+		var cmd tea.Cmd
+		switch {
+		case m.ShowModels:
+			m.ModelList, cmd = m.ModelList.Update(msg)
+		case m.ShowSessions:
+			m.SessionList, cmd = m.SessionList.Update(msg)
 		}
+		cmds = append(cmds, cmd)
 	case LogUpdateMsg:
 		m.Log.SetContent(m.Log.View() + string(msg) + "\n")
 		m.Log.GotoBottom()
@@ -167,6 +205,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ShowError = false
 		m.ErrorMessage = ""
 		return m, nil
+	case noticeMsg:
+		m.Notice = msg.text
+		m.noticeSeq++
+		seq := m.noticeSeq
+		return m, tea.Tick(noticeDuration, func(time.Time) tea.Msg { return clearNoticeMsg{seq} })
+	case clearNoticeMsg:
+		if msg.seq == m.noticeSeq {
+			m.Notice = ""
+		}
+		return m, nil
 	default:
 		var cmd tea.Cmd
 		updatedChat, cmd := m.Chat.Update(msg)
@@ -176,33 +224,115 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+func (m *Model) handleStreamEvent(msg StreamEventMsg) tea.Cmd {
+	// Events from a stream that was stopped or replaced are dropped; its
+	// goroutine notices the cancelled context and exits on its own.
+	if msg.events == nil || msg.events != m.Chat.stream {
+		return nil
+	}
+	ev := msg.Event
+	switch ev.Type {
+	case llm.EventContent:
+		m.Chat.appendStreamChunk(ev.Content)
+	case llm.EventToolCall:
+		log.Printf("stream: model requested %d tool call(s)", len(ev.ToolCalls))
+	case llm.EventDone:
+		cmd := m.Chat.finishRound(ev.Response)
+		m.updateStatusBar()
+		return cmd
+	case llm.EventError:
+		m.Chat.endStream()
+		return func() tea.Msg { return errorMsg{ev.Err} }
+	}
+	return WaitForStreamEventCmd(msg.events)
+}
+
 func (m *Model) handleKeyMsg(msg tea.KeyMsg) tea.Cmd {
 	var cmds []tea.Cmd
-	switch msg.String() {
-	case "q", "ctrl+c":
+	if m.ShowSessions {
+		return m.handleSessionBrowserKey(msg)
+	}
+	if m.ShowModels {
+		return m.handleModelPickerKey(msg)
+	}
+	if m.Chat.approving {
+		return m.handleApprovalKey(msg)
+	}
+	if m.Chat.search != nil {
+		return m.handleSearchKey(msg)
+	}
+	if key.Matches(msg, m.Keys.HistorySearch) && m.Chat.Input.Focused() {
+		m.Chat.startSearch(m.Keys.HistorySearch.Help().Key)
+		return nil
+	}
+	if key.Matches(msg, m.Keys.Sessions) {
+		return m.openSessionBrowser()
+	}
+	if key.Matches(msg, m.Keys.Models) {
+		return listModelsCmd(m.Chat.LlmClient)
+	}
+	if key.Matches(msg, m.Keys.Editor) {
+		return openEditorCmd(m.Chat.Input.Value())
+	}
+	if key.Matches(msg, m.Keys.Stop) && m.Chat.Streaming {
+		m.Chat.StopStreaming()
+		return nil
+	}
+	if key.Matches(msg, m.Keys.Stop) && m.pull != nil {
+		m.cancelPull()
+		return nil
+	}
+	// Printable keys typed into the input are text, not shortcuts, even
+	// when an action such as quit is bound to a letter.
+	typing := m.Chat.Input.Focused() && (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace)
+	switch {
+	case typing:
+	case key.Matches(msg, m.Keys.Quit):
 		return tea.Quit
-	case "?":
+	case key.Matches(msg, m.Keys.Help):
 		m.ShowHelp = !m.ShowHelp
 		return nil
-	case "enter":
-		if m.Chat.TextInput.Focused() {
-			userMsg := m.Chat.TextInput.Value()
-			if userMsg != "" {
-				m.Chat.Messages = append(m.Chat.Messages, llm.Message{Role: "user", Content: userMsg})
-				m.Chat.List.InsertItem(len(m.Chat.List.Items()), Item(userMsg))
-				m.Chat.TextInput.SetValue("")
-				m.Chat.Streaming = true
-				return StreamLLMResponseCmd(m.Chat.LlmClient, m.Chat.Messages)
+	case msg.String() == "enter":
+		if m.Chat.Input.Focused() && !m.Chat.Streaming {
+			userMsg := m.Chat.Input.Value()
+			if name, _, ok := parseCommand(userMsg); ok {
+				// An unknown command is reported with the text left in
+				// place, to fix a typo or escape a leading slash.
+				if _, known := m.commands().Get(name); !known {
+					return m.runCommand(userMsg)
+				}
+				m.Chat.Input.SetValue("")
+				m.Chat.recordHistory(userMsg)
+				return m.runCommand(userMsg)
+			}
+			if text := strings.TrimPrefix(userMsg, "/"); strings.TrimSpace(text) != "" {
+				m.Chat.Input.SetValue("")
+				cmd := m.Chat.Send(text)
+				// Recorded after Send, which starts the session it belongs to.
+				m.Chat.recordHistory(userMsg)
+				m.updateStatusBar()
+				return cmd
 			}
 		}
-	case "tab":
+	// Up on the first line and down on the last browse the history; within
+	// a multi-line draft they move the cursor.
+	case msg.Type == tea.KeyUp && m.Chat.Input.Focused() && m.Chat.Input.Line() == 0 && m.Chat.historyPrev():
+		return nil
+	case msg.Type == tea.KeyDown && m.Chat.Input.Focused() && m.Chat.Input.Line() == m.Chat.Input.LineCount()-1 && m.Chat.historyNext():
+		return nil
+	case key.Matches(msg, m.Keys.Tab) && m.Chat.Input.Focused() && strings.HasPrefix(m.Chat.Input.Value(), "/"):
+		return m.completeInput()
+	case key.Matches(msg, m.Keys.Tab):
+		// The input only has focus in the chat pane, so the single-key
+		// shortcuts work in the log pane.
 		if m.ActivePane == ChatPane {
 			m.ActivePane = LogPane
-		} else {
-			m.ActivePane = ChatPane
+			m.Chat.Input.Blur()
+			return nil
 		}
-		return nil
-	case "t":
+		m.ActivePane = ChatPane
+		return m.Chat.Input.Focus()
+	case key.Matches(msg, m.Keys.ToggleTheme):
 		if m.Theme.Name == DarkTheme.Name {
 			m.Theme = LightTheme
 		} else {
@@ -262,11 +392,34 @@ func (m *Model) handleWindowSizeMsg(msg tea.WindowSizeMsg) tea.Cmd {
 	// The actual viewport height will be set in ChatModel.View()
 	m.Chat.Viewport.Width = m.Chat.Width
 
-	// Update status bar text
-	m.StatusBarText = fmt.Sprintf("Model: %s | Host: %s", m.Chat.LlmClient.Model(), m.Chat.LlmClient.Host())
+	m.updateStatusBar()
 	return nil
 }
 
+// updateStatusBar refreshes the status bar text from the client settings.
+func (m *Model) updateStatusBar() {
+	m.StatusBarText = fmt.Sprintf("Model: %s | Host: %s", m.Chat.LlmClient.Model(), m.Chat.LlmClient.Host())
+	if opts := m.Chat.LlmClient.Options(); !opts.IsZero() {
+		m.StatusBarText += " | " + opts.String()
+	}
+	if turn := m.Chat.TurnUsage; turn.Responses > 0 {
+		m.StatusBarText += fmt.Sprintf(" | turn: %s, ttft %.1fs", turn, turn.TimeToFirstToken.Seconds())
+	}
+	if session, ok := m.Chat.SessionUsage[m.Chat.LlmClient.Model()]; ok {
+		m.StatusBarText += " | session: " + session.String()
+	}
+	usage := m.Chat.ContextUsage()
+	m.StatusBarText += fmt.Sprintf(" | ctx %s %s", contextGauge(usage.Fraction()), usage)
+}
+
+// contextGauge draws how full the context window is as a row of blocks.
+func contextGauge(fraction float64) string {
+	const cells = 8
+	filled := int(fraction*cells + 0.5)
+	filled = max(0, min(filled, cells))
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", cells-filled)
+}
+
 func (m *Model) View() string {
 	log.Printf("model.View called: Width=%d, Height=%d", m.Width, m.Height)
 
@@ -316,20 +469,37 @@ func (m *Model) View() string {
 
 	mainView := lipgloss.JoinHorizontal(lipgloss.Top, chatView, logView)
 	log.Printf("model.View: mainView rendered height: %d", lipgloss.Height(mainView))
-	statusBarRendered := m.Theme.StatusBar.Width(m.Width).Render(m.StatusBarText)
+	statusBarRendered := m.Theme.StatusBar.Width(m.Width).MaxHeight(1).Render(m.StatusBarText)
 	log.Printf("model.View: statusBarRendered height: %d", lipgloss.Height(statusBarRendered))
 
 	layout := lipgloss.JoinVertical(lipgloss.Left,
 		mainView,
 		statusBarRendered,
 	)
+	if m.pull != nil {
+		layout = lipgloss.JoinVertical(lipgloss.Left, layout, m.pullView())
+	}
 	log.Printf("model.View: layout rendered height (before error/help): %d", lipgloss.Height(layout))
 
+	if m.Notice != "" {
+		noticeStyle := lipgloss.NewStyle().Background(m.Theme.Primary2).Foreground(m.Theme.Accent2).Padding(0, 1)
+		layout = lipgloss.JoinVertical(lipgloss.Left, layout, noticeStyle.Width(m.Width).Render(m.Notice))
+	}
 	if m.ShowError && m.ErrorMessage != "" {
 		layout = lipgloss.JoinVertical(lipgloss.Left, layout, m.ErrorBanner.Width(m.Width).Render(m.ErrorMessage))
 		log.Printf("model.View: layout rendered height (after error banner): %d", lipgloss.Height(layout))
 	}
 
+	if m.ShowSessions {
+		return m.sessionBrowserView()
+	}
+	if m.ShowModels {
+		return m.modelPickerView()
+	}
+	if m.Chat.approving {
+		return m.approvalView()
+	}
+
 	if m.ShowHelp {
 		helpBox := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
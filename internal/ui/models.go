@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// modelsMsg carries the result of listing the server's models.
+type modelsMsg struct {
+	models []llm.ModelInfo
+	err    error
+}
+
+func listModelsCmd(client *llm.Client) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		models, err := client.ListModels(ctx)
+		return modelsMsg{models: models, err: err}
+	}
+}
+
+// openModelPicker shows the installed models in a modal, already filtering
+// so typing narrows the list straight away.
+func (m *Model) openModelPicker(msg modelsMsg) tea.Cmd {
+	if msg.err != nil {
+		return func() tea.Msg { return errorMsg{msg.err} }
+	}
+	current := m.Chat.LlmClient.Model()
+	items := make([]list.Item, 0, len(msg.models))
+	m.modelNames = m.modelNames[:0]
+	selected := 0
+	for i, info := range msg.models {
+		items = append(items, modelItem{ModelInfo: info, current: info.Name == current})
+		m.modelNames = append(m.modelNames, info.Name)
+		if info.Name == current {
+			selected = i
+		}
+	}
+	m.ModelList = list.New(items, list.NewDefaultDelegate(), 0, 0)
+	m.ModelList.Title = "Models · enter an unknown name to pull it"
+	m.ModelList.SetShowHelp(false)
+	m.ModelList.Styles.Title = m.ModelList.Styles.Title.Background(m.Theme.Primary2).Foreground(m.Theme.Accent2)
+	m.ModelList.SetFilterText("")
+	m.ModelList.SetFilterState(list.Filtering)
+	m.ModelList.Select(selected)
+	m.ShowModels = true
+	return nil
+}
+
+// handleModelPickerKey routes keys to the model picker while it is open:
+// enter switches to the highlighted model, esc closes the picker, and
+// everything else drives the list and its filter. A typed name that is
+// installed is switched to even if the filter highlights another; one
+// that matches nothing is pulled.
+func (m *Model) handleModelPickerKey(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case msg.String() == "ctrl+c":
+		return tea.Quit
+	case msg.String() == "esc" || key.Matches(msg, m.Keys.Models):
+		m.ShowModels = false
+		return nil
+	case msg.String() == "enter":
+		m.ShowModels = false
+		typed := strings.TrimSpace(m.ModelList.FilterValue())
+		for _, item := range m.ModelList.Items() {
+			if item, ok := item.(modelItem); ok && typed != "" && item.Name == typed {
+				return m.switchModel(item.Name)
+			}
+		}
+		if item, ok := m.ModelList.SelectedItem().(modelItem); ok {
+			return m.switchModel(item.Name)
+		}
+		if typed == "" {
+			return nil
+		}
+		return m.startPull(typed)
+	}
+	var cmd tea.Cmd
+	m.ModelList, cmd = m.ModelList.Update(msg)
+	return cmd
+}
+
+// switchModel makes name the model for the rest of the conversation,
+// including when it is resumed.
+func (m *Model) switchModel(name string) tea.Cmd {
+	m.Chat.LlmClient.SetModel(name)
+	m.Chat.saveSessionInfo()
+	m.updateStatusBar()
+	return nil
+}
+
+func (m *Model) modelPickerView() string {
+	width := max(m.Width*2/3, 20)
+	height := max(m.Height*2/3, 8)
+	m.ModelList.SetSize(width-4, height-2)
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.Theme.Accent1).
+		Padding(0, 1).
+		Render(m.ModelList.View())
+	return lipgloss.Place(m.Width, m.Height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// pullState tracks a model download started from the picker.
+type pullState struct {
+	name     string
+	events   <-chan llm.PullEvent
+	cancel   context.CancelFunc
+	progress llm.PullProgress
+}
+
+// PullEventMsg delivers one event of an in-flight model download along
+// with the channel to keep reading from.
+type PullEventMsg struct {
+	Event  llm.PullEvent
+	events <-chan llm.PullEvent
+}
+
+func waitForPullEventCmd(events <-chan llm.PullEvent) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return PullEventMsg{Event: llm.PullEvent{Err: context.Canceled}}
+		}
+		return PullEventMsg{Event: ev, events: events}
+	}
+}
+
+// startPull downloads name in the background, replacing any pull already
+// running.
+func (m *Model) startPull(name string) tea.Cmd {
+	m.cancelPull()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := m.Chat.LlmClient.PullModel(ctx, name)
+	m.pull = &pullState{name: name, events: events, cancel: cancel, progress: llm.PullProgress{Status: "starting"}}
+	return waitForPullEventCmd(events)
+}
+
+func (m *Model) cancelPull() {
+	if m.pull != nil {
+		m.pull.cancel()
+		m.pull = nil
+	}
+}
+
+// handlePullEvent records download progress. A finished pull switches to
+// the new model.
+func (m *Model) handlePullEvent(msg PullEventMsg) tea.Cmd {
+	if m.pull == nil || msg.events != m.pull.events {
+		return nil
+	}
+	ev := msg.Event
+	switch {
+	case ev.Err != nil:
+		name := m.pull.name
+		m.cancelPull()
+		return func() tea.Msg { return errorMsg{fmt.Errorf("pulling %s: %w", name, ev.Err)} }
+	case ev.Done:
+		name := m.pull.name
+		m.cancelPull()
+		return m.switchModel(name)
+	}
+	m.pull.progress = ev.Progress
+	return waitForPullEventCmd(msg.events)
+}
+
+// pullView is the progress line shown while a model downloads.
+func (m *Model) pullView() string {
+	p := m.pull.progress
+	label := fmt.Sprintf("Pulling %s: %s ", m.pull.name, p.Status)
+	line := label
+	if p.Total > 0 {
+		bar := progress.New(
+			progress.WithGradient(string(m.Theme.Primary3), string(m.Theme.Accent1)),
+			progress.WithWidth(max(m.Width-lipgloss.Width(label)-2, 10)),
+		)
+		line += bar.ViewAs(p.Fraction())
+	}
+	return lipgloss.NewStyle().Width(m.Width).Foreground(m.Theme.Accent2).Background(m.Theme.BgDark).Render(line)
+}
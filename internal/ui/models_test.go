@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// applyFilterMatches runs cmd and feeds any list filter results back into m.
+func applyFilterMatches(m *Model, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			applyFilterMatches(m, c)
+		}
+	case list.FilterMatchesMsg:
+		m.Update(msg)
+	}
+}
+
+func TestModelPickerSwitchesModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"models":[
+			{"name":"llama3:8b","size":4661224676,"details":{"parameter_size":"8.0B","quantization_level":"Q4_0"}},
+			{"name":"qwen2.5-coder:7b","size":4683087332,"details":{"parameter_size":"7.6B","quantization_level":"Q4_K_M"}}
+		]}`)
+	}))
+	defer srv.Close()
+
+	m := &Model{Keys: DefaultKeyMap, Theme: DarkTheme, Width: 80, Height: 24}
+	m.Chat.LlmClient = llm.NewClient(srv.URL, "llama3:8b", "")
+
+	cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if cmd == nil {
+		t.Fatal("expected ctrl+p to list models")
+	}
+	m.Update(cmd())
+	if !m.ShowModels || len(m.ModelList.Items()) != 2 {
+		t.Fatalf("picker not shown with both models: %v", m.ModelList.Items())
+	}
+	if item := m.ModelList.SelectedItem().(modelItem); !item.current || item.Description() != "8.0B · Q4_0 · 4.7 GB" {
+		t.Errorf("current model not preselected: %+v %q", item, item.Description())
+	}
+
+	m.ModelList.FilterInput.Cursor.SetMode(cursor.CursorStatic) // no blink ticks
+	for _, r := range "qwen" {
+		applyFilterMatches(m, m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}))
+	}
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.ShowModels {
+		t.Error("picker should close after choosing")
+	}
+	if got := m.Chat.LlmClient.Model(); got != "qwen2.5-coder:7b" {
+		t.Errorf("model = %q", got)
+	}
+	if !strings.Contains(m.StatusBarText, "qwen2.5-coder:7b") {
+		t.Errorf("status bar not updated: %q", m.StatusBarText)
+	}
+}
+
+func TestModelPickerPullsUnknownName(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprintln(w, `{"models":[{"name":"llama3:8b"}]}`)
+		case "/api/pull":
+			fmt.Fprintln(w, `{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":40}`)
+			fmt.Fprintln(w, `{"status":"success"}`)
+		}
+	}))
+	defer srv.Close()
+
+	m := &Model{Keys: DefaultKeyMap, Theme: DarkTheme, Width: 80, Height: 24}
+	m.Chat.LlmClient = llm.NewClient(srv.URL, "llama3:8b", "")
+	m.Update(listModelsCmd(m.Chat.LlmClient)())
+	m.ModelList.SetFilterText("llama3:8b")
+	m.ModelList.SetFilterState(list.Filtering)
+	if cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || m.pull != nil {
+		t.Fatal("an installed name should be switched to, not pulled")
+	}
+
+	// A name that matches nothing installed is pulled as typed.
+	m.Update(listModelsCmd(m.Chat.LlmClient)())
+	m.ModelList.SetFilterText("llama3.2:1b")
+	m.ModelList.SetFilterState(list.Filtering)
+	cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || m.pull == nil || m.pull.name != "llama3.2:1b" {
+		t.Fatal("expected enter on an unknown name to start a pull")
+	}
+	m.Update(cmd())
+	if m.pull == nil || m.pull.progress.Completed != 40 {
+		t.Fatalf("progress not recorded: %+v", m.pull)
+	}
+	for m.pull != nil {
+		m.handlePullEvent(waitForPullEventCmd(m.pull.events)().(PullEventMsg))
+	}
+	if m.Chat.LlmClient.Model() != "llama3.2:1b" {
+		t.Errorf("expected the pulled model to become active, got %q", m.Chat.LlmClient.Model())
+	}
+
+	m.Update(listModelsCmd(m.Chat.LlmClient)())
+	m.ModelList.SetFilterText("phi3")
+	cmd = m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || m.pull == nil {
+		t.Fatal("expected enter on an unknown name to start a pull")
+	}
+
+	m.Update(cmd())
+	if m.pull == nil || m.pull.progress.Completed != 40 {
+		t.Fatalf("progress not recorded: %+v", m.pull)
+	}
+	if view := m.pullView(); !strings.Contains(view, "Pulling phi3") {
+		t.Errorf("unexpected progress view %q", view)
+	}
+	for m.pull != nil {
+		m.handlePullEvent(waitForPullEventCmd(m.pull.events)().(PullEventMsg))
+	}
+	if m.pull != nil || m.Chat.LlmClient.Model() != "phi3" {
+		t.Errorf("expected the pulled model to become active, got %q", m.Chat.LlmClient.Model())
+	}
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openSessionBrowser lists stored sessions in a modal.
+func (m *Model) openSessionBrowser() tea.Cmd {
+	if m.Chat.Sessions == nil {
+		return func() tea.Msg { return errorMsg{fmt.Errorf("session storage is disabled")} }
+	}
+	summaries, err := m.Chat.Sessions.List()
+	if err != nil {
+		return func() tea.Msg { return errorMsg{err} }
+	}
+	items := make([]list.Item, 0, len(summaries))
+	for _, s := range summaries {
+		items = append(items, sessionItem{s})
+	}
+	m.SessionList = list.New(items, list.NewDefaultDelegate(), 0, 0)
+	m.SessionList.Title = "Sessions"
+	m.SessionList.SetShowHelp(false)
+	m.SessionList.Styles.Title = m.SessionList.Styles.Title.Background(m.Theme.Primary2).Foreground(m.Theme.Accent2)
+	m.ShowSessions = true
+	return nil
+}
+
+// handleSessionBrowserKey routes keys to the session browser while it is
+// open: enter loads the selection, esc closes it unless a filter is being
+// typed, and everything else drives the list.
+func (m *Model) handleSessionBrowserKey(msg tea.KeyMsg) tea.Cmd {
+	filtering := m.SessionList.FilterState() == list.Filtering
+	switch {
+	case msg.String() == "ctrl+c":
+		return tea.Quit
+	case !filtering && (msg.String() == "esc" || key.Matches(msg, m.Keys.Sessions)):
+		m.ShowSessions = false
+		return nil
+	case !filtering && msg.String() == "enter":
+		item, ok := m.SessionList.SelectedItem().(sessionItem)
+		if !ok {
+			return nil
+		}
+		sess, err := m.Chat.Sessions.Load(item.ID)
+		if err != nil {
+			return func() tea.Msg { return errorMsg{err} }
+		}
+		m.Chat.LoadSession(sess)
+		m.ShowSessions = false
+		return nil
+	}
+	var cmd tea.Cmd
+	m.SessionList, cmd = m.SessionList.Update(msg)
+	return cmd
+}
+
+func (m *Model) sessionBrowserView() string {
+	width := max(m.Width*2/3, 20)
+	height := max(m.Height*2/3, 8)
+	m.SessionList.SetSize(width-4, height-2)
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.Theme.Accent1).
+		Padding(0, 1).
+		Render(m.SessionList.View())
+	return lipgloss.Place(m.Width, m.Height, lipgloss.Center, lipgloss.Center, box)
+}
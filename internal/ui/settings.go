@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setOption implements `/set <option> [value]`, changing a generation
+// option for the rest of the session. Leaving out the value resets the
+// option to the server default.
+func (m *Model) setOption(args string) tea.Cmd {
+	name, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+	if name == "" {
+		return func() tea.Msg {
+			return errorMsg{fmt.Errorf("usage: /set <option> [value], options: %s", strings.Join(llm.OptionNames(), ", "))}
+		}
+	}
+	opts := m.Chat.LlmClient.Options()
+	if err := opts.Set(name, value); err != nil {
+		return func() tea.Msg { return errorMsg{err} }
+	}
+	m.Chat.LlmClient.SetOptions(opts)
+	m.updateStatusBar()
+	return nil
+}
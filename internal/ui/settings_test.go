@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSetCommandChangesOptions(t *testing.T) {
+	m := &Model{Keys: DefaultKeyMap, Theme: DarkTheme}
+	m.Chat.LlmClient = llm.NewClient("http://unused", "test", "")
+	m.Chat.Input = NewInput(0)
+	m.Chat.Input.Focus()
+
+	m.Chat.Input.SetValue("/set temperature 0.5")
+	if cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatalf("unexpected command result %v", cmd())
+	}
+	if o := m.Chat.LlmClient.Options(); o.Temperature == nil || *o.Temperature != 0.5 {
+		t.Errorf("temperature not set: %s", o)
+	}
+	if !strings.Contains(m.StatusBarText, "temp=0.5") || m.Chat.Input.Value() != "" {
+		t.Errorf("status bar %q, input %q", m.StatusBarText, m.Chat.Input.Value())
+	}
+	if len(m.Chat.Messages) != 0 {
+		t.Error("/set must not be sent to the model")
+	}
+
+	m.Chat.Input.SetValue("/set temperature hot")
+	cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := cmd().(errorMsg); !ok {
+		t.Error("expected an error for an invalid value")
+	}
+}
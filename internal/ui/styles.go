@@ -61,4 +61,14 @@ func (t *Theme) ApplyStyles() {
 	t.UserMessage = lipgloss.NewStyle().Background(t.BgLight).Foreground(t.BgDark).Bold(true).Padding(0, 1)
 	t.AssistantMessage = lipgloss.NewStyle().Background(t.Primary1).Foreground(t.Accent2).Padding(0, 1)
 	t.ToolMessage = lipgloss.NewStyle().Background(t.Primary3).Foreground(t.BgLight).Italic(true).Padding(0, 1)
-}
\ No newline at end of file
+}
+
+// ThemeByName returns the built-in theme with the given name.
+func ThemeByName(name string) (Theme, bool) {
+	for _, t := range []Theme{DarkTheme, LightTheme} {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Theme{}, false
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"clai/internal/llm"
+	"clai/internal/tools"
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// maxToolOutput is how much of a running tool's output is kept for the
+	// panel, in bytes.
+	maxToolOutput = 16 * 1024
+	// toolOutputLines is how many of its last lines the panel shows.
+	toolOutputLines = 8
+)
+
+// toolEvent is output from a running tool call or, once it has finished,
+// its result.
+type toolEvent struct {
+	output string
+	result *llm.Message
+}
+
+// toolOutputMsg delivers output from a running tool call along with the
+// channel to keep reading from. ctx identifies the round the call belongs
+// to.
+type toolOutputMsg struct {
+	text   string
+	ctx    context.Context
+	events <-chan toolEvent
+}
+
+// eventWriter passes what a tool writes on to the UI, giving up once the
+// call is cancelled and nobody is reading.
+type eventWriter struct {
+	ctx    context.Context
+	events chan<- toolEvent
+}
+
+func (w eventWriter) Write(p []byte) (int, error) {
+	select {
+	case w.events <- toolEvent{output: string(p)}:
+	case <-w.ctx.Done():
+	}
+	return len(p), nil
+}
+
+// runToolCallCmd runs an approved call. Output the tool writes while it
+// runs arrives as toolOutputMsgs before the ToolResultMsg.
+func runToolCallCmd(ctx context.Context, client *llm.Client, call llm.ToolCall) tea.Cmd {
+	return func() tea.Msg {
+		events := make(chan toolEvent)
+		go func() {
+			result := client.RunToolCall(tools.WithOutput(ctx, eventWriter{ctx, events}), call)
+			select {
+			case events <- toolEvent{result: &result}:
+			case <-ctx.Done():
+			}
+		}()
+		return waitForToolEvent(ctx, events)
+	}
+}
+
+func waitForToolEvent(ctx context.Context, events <-chan toolEvent) tea.Msg {
+	select {
+	case ev := <-events:
+		if ev.result != nil {
+			return ToolResultMsg{ToolName: ev.result.ToolName, Result: ev.result.Content, ctx: ctx}
+		}
+		return toolOutputMsg{text: ev.output, ctx: ctx, events: events}
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// handleToolOutput adds output to the panel and waits for more. Output of
+// a call that was stopped is dropped.
+func (c *ChatModel) handleToolOutput(msg toolOutputMsg) tea.Cmd {
+	if msg.ctx != c.toolCtx {
+		return nil
+	}
+	c.toolOutput += msg.text
+	if over := len(c.toolOutput) - maxToolOutput; over > 0 {
+		c.toolOutput = c.toolOutput[over:]
+	}
+	return func() tea.Msg { return waitForToolEvent(msg.ctx, msg.events) }
+}
+
+// toolOutputView shows the last lines written by the running tool in a
+// panel above the spinner.
+func (c *ChatModel) toolOutputView() string {
+	width := max(c.Width-4, 1)
+	lines := strings.Split(strings.TrimRight(ansi.Strip(c.toolOutput), "\n"), "\n")
+	if len(lines) > toolOutputLines {
+		lines = lines[len(lines)-toolOutputLines:]
+	}
+	for i, l := range lines {
+		// A carriage return redraws the line, as progress bars do.
+		l = strings.TrimSuffix(l, "\r")
+		if j := strings.LastIndexByte(l, '\r'); j >= 0 {
+			l = l[j+1:]
+		}
+		lines[i] = ansi.Truncate(strings.ReplaceAll(l, "\t", "    "), width, "…")
+	}
+	title := lipgloss.NewStyle().Foreground(c.Theme.Accent2).Faint(true).
+		Render(c.pendingToolCalls[0].Name + " output")
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(c.Theme.Primary2).
+		Padding(0, 1).
+		Width(max(c.Width-2, 1)).
+		Render(lipgloss.JoinVertical(lipgloss.Left, append([]string{title}, lines...)...))
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"clai/internal/tools"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunCommandStreamsIntoPanel(t *testing.T) {
+	if _, err := exec.LookPath("printf"); err != nil {
+		t.Skip("no printf")
+	}
+	m := newCommandModel(t)
+	m.Width, m.Height = 80, 40
+	m.Chat.Width, m.Chat.Height = 80, 30
+	m.Chat.LlmClient.SetPermissions(tools.NewPermissions(tools.PolicyAsk, nil))
+
+	callTool(m, "run_command", `{"command":"printf 'building\\nstep 2\\n'"}`)
+	if !m.Chat.approving {
+		t.Fatal("run_command should wait for approval")
+	}
+	msg := typeRunes(m, "y")()
+	var result ToolResultMsg
+	streamed := false
+	for i := 0; i < 10; i++ {
+		out, ok := msg.(toolOutputMsg)
+		if !ok {
+			result, _ = msg.(ToolResultMsg)
+			break
+		}
+		streamed = true
+		cmd := m.Chat.handleToolOutput(out)
+		if view := m.Chat.View(); !strings.Contains(view, "run_command output") || !strings.Contains(view, "building") {
+			t.Errorf("output panel not shown:\n%s", view)
+		}
+		msg = cmd()
+	}
+	if !streamed {
+		t.Error("no output arrived before the result")
+	}
+	if !strings.Contains(result.Result, "exit code: 0") || !strings.Contains(result.Result, "step 2") {
+		t.Fatalf("result = %+v", result)
+	}
+	if m.Chat.handleToolResult(result); m.Chat.toolOutput != "" {
+		t.Error("output panel not cleared after the call")
+	}
+	m.Chat.StopStreaming()
+}